@@ -6,9 +6,11 @@ import (
 	"math"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/libp2p/go-libp2p-routing-helpers/tracing"
+	"github.com/libp2p/go-libp2p/core/event"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -119,6 +121,12 @@ type IpfsDHT struct {
 	alpha      int // The concurrency parameter per path
 	beta       int // The number of peers closest to a target that must have responded for a query path to terminate
 
+	// bounds of the adaptive concurrency per path, maxAlpha is 0 if the concurrency is fixed to alpha
+	minAlpha, maxAlpha int
+
+	// minPutSuccesses is the number of peers that must store a record for PutValue to succeed
+	minPutSuccesses int
+
 	queryPeerFilter        QueryFilterFunc
 	routingTablePeerFilter RouteTableFilterFunc
 	rtPeerDiversityFilter  peerdiversity.PeerIPGroupFilter
@@ -136,6 +144,9 @@ type IpfsDHT struct {
 	// connecting to the network).
 	bootstrapPeers func() []peer.AddrInfo
 
+	// interval at which the Routing Table peers are persisted to the datastore, zero if disabled.
+	rtSnapshotInterval time.Duration
+
 	maxRecordAge time.Duration
 
 	// Allows disabling dht subsystems. These should _only_ be set on
@@ -164,6 +175,17 @@ type IpfsDHT struct {
 	// addrFilter is used to filter the addresses we put into the peer store.
 	// Mostly used to filter out localhost and local addresses.
 	addrFilter func([]ma.Multiaddr) []ma.Multiaddr
+
+	// rateLimiter limits the rate of inbound requests we serve, nil if disabled.
+	rateLimiter *serverRateLimiter
+
+	// recordValidator validates inbound PUT_VALUE records and the records we read from the datastore.
+	recordValidator *recordValidator
+
+	// rtEmptyEmitter emits EvtRoutingTableEmpty, nil if the routing table is not fixed when low on peers
+	rtEmptyEmitter event.Emitter
+	// rtHadPeers is set when a peer is added to the routing table, and cleared once we noticed it became empty
+	rtHadPeers atomic.Bool
 }
 
 // Assert that IPFS assumptions about interfaces aren't broken. These aren't a
@@ -241,6 +263,10 @@ func New(ctx context.Context, h host.Host, options ...Option) (*IpfsDHT, error)
 
 	dht.rtPeerLoop()
 
+	if dht.rtSnapshotInterval > 0 {
+		dht.runRTSnapshotLoop()
+	}
+
 	// Fill routing table with currently connected peers that are DHT servers
 	for _, p := range dht.host.Network().Peers() {
 		dht.peerFound(p)
@@ -250,6 +276,10 @@ func New(ctx context.Context, h host.Host, options ...Option) (*IpfsDHT, error)
 
 	// listens to the fix low peers chan and tries to fix the Routing Table
 	if !dht.disableFixLowPeers {
+		dht.rtEmptyEmitter, err = h.EventBus().Emitter(new(EvtRoutingTableEmpty))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create routing table empty event emitter: %w", err)
+		}
 		dht.runFixLowPeersLoop()
 	}
 
@@ -301,6 +331,9 @@ func makeDHT(h host.Host, cfg dhtcfg.Config) (*IpfsDHT, error) {
 		serverProtocols:        serverProtocols,
 		bucketSize:             cfg.BucketSize,
 		alpha:                  cfg.Concurrency,
+		minAlpha:               cfg.AdaptiveConcurrency.Min,
+		maxAlpha:               cfg.AdaptiveConcurrency.Max,
+		minPutSuccesses:        cfg.MinPutSuccesses,
 		beta:                   cfg.Resiliency,
 		lookupCheckCapacity:    cfg.LookupCheckConcurrency,
 		queryPeerFilter:        cfg.QueryPeerFilter,
@@ -339,6 +372,7 @@ func makeDHT(h host.Host, cfg dhtcfg.Config) (*IpfsDHT, error) {
 	}
 	dht.routingTable = rt
 	dht.bootstrapPeers = cfg.BootstrapPeers
+	dht.rtSnapshotInterval = cfg.RoutingTable.SnapshotInterval
 
 	dht.lookupCheckTimeout = cfg.RoutingTable.RefreshQueryTimeout
 
@@ -370,6 +404,16 @@ func makeDHT(h host.Host, cfg dhtcfg.Config) (*IpfsDHT, error) {
 
 	dht.rtFreezeTimeout = rtFreezeTimeout
 
+	dht.rateLimiter, err = newServerRateLimiter(cfg.ServerRateLimit.Global, cfg.ServerRateLimit.PerPeer)
+	if err != nil {
+		return nil, fmt.Errorf("initializing server rate limiter (%v)", err)
+	}
+
+	dht.recordValidator, err = newRecordValidator(cfg.ValidationConcurrency)
+	if err != nil {
+		return nil, fmt.Errorf("initializing record validator (%v)", err)
+	}
+
 	return dht, nil
 }
 
@@ -438,6 +482,7 @@ func makeRoutingTable(dht *IpfsDHT, cfg dhtcfg.Config, maxLastSuccessfulOutbound
 		} else {
 			cmgr.TagPeer(p, kbucketTag, baseConnMgrScore)
 		}
+		dht.rtHadPeers.Store(true)
 	}
 	rt.PeerRemoved = func(p peer.ID) {
 		cmgr.Unprotect(p, kbucketTag)
@@ -465,51 +510,109 @@ func (dht *IpfsDHT) Mode() ModeOpt {
 	return dht.auto
 }
 
-// runFixLowPeersLoop manages simultaneous requests to fixLowPeers
+// runFixLowPeersLoop manages simultaneous requests to fixLowPeers.
+//
+// While we are offline the attempts are paused, and they resume as soon as we are back online. If an attempt fails to
+// connect to any peer while the routing table is empty, the next one is delayed with an exponential backoff.
 func (dht *IpfsDHT) runFixLowPeersLoop() {
+	backoff := newBootstrapBackoff()
+
 	dht.wg.Add(1)
 	go func() {
 		defer dht.wg.Done()
 
-		dht.fixLowPeers()
-
 		ticker := time.NewTicker(periodicBootstrapInterval)
 		defer ticker.Stop()
 
+		retry := time.NewTimer(0)
+		<-retry.C
+		defer retry.Stop()
+
+		var (
+			retryAt time.Time // when the backoff is over, zero if not backing off
+			paused  bool
+		)
+		fix := func() {
+			if !isOnline(dht.host.Network()) {
+				if !paused {
+					logger.Infow("no network connectivity, pausing attempts to fix the routing table")
+					paused = true
+				}
+				return
+			}
+			if paused {
+				logger.Infow("network connectivity is back, fixing the routing table")
+				paused = false
+				backoff.reset()
+				retryAt = time.Time{}
+			} else if time.Now().Before(retryAt) {
+				return
+			}
+
+			if !dht.fixLowPeers() {
+				d := backoff.next()
+				logger.Debugw("failed to connect to any peer, backing off", "delay", d)
+				retryAt = time.Now().Add(d)
+				if !retry.Stop() {
+					select {
+					case <-retry.C:
+					default:
+					}
+				}
+				retry.Reset(d)
+			} else {
+				backoff.reset()
+				retryAt = time.Time{}
+			}
+		}
+
+		fix()
 		for {
 			select {
 			case <-dht.fixLowPeersChan:
 			case <-ticker.C:
+			case <-retry.C:
 			case <-dht.ctx.Done():
 				return
 			}
 
-			dht.fixLowPeers()
+			if dht.routingTable.Size() == 0 && dht.rtHadPeers.CompareAndSwap(true, false) {
+				if err := dht.rtEmptyEmitter.Emit(EvtRoutingTableEmpty{}); err != nil {
+					logger.Warnw("failed to emit routing table empty event", "error", err)
+				}
+			}
+			fix()
 		}
 	}()
 }
 
-// fixLowPeers tries to get more peers into the routing table if we're below the threshold
-func (dht *IpfsDHT) fixLowPeers() {
+// fixLowPeers tries to get more peers into the routing table if we're below the threshold. It returns false if the
+// routing table is empty and we could not connect to any peer that may fill it.
+func (dht *IpfsDHT) fixLowPeers() bool {
 	if dht.routingTable.Size() > minRTRefreshThreshold {
-		return
+		return true
 	}
 
 	// we try to add all peers we are connected to to the Routing Table
 	// in case they aren't already there.
-	for _, p := range dht.host.Network().Peers() {
+	connected := dht.host.Network().Peers()
+	for _, p := range connected {
 		dht.peerFound(p)
 	}
 
-	// TODO Active Bootstrapping
-	// We should first use non-bootstrap peers we knew of from previous
-	// snapshots of the Routing Table before we connect to the bootstrappers.
+	// We first use the non-bootstrap peers we knew of from the previous
+	// snapshot of the Routing Table before we connect to the bootstrappers.
 	// See https://github.com/libp2p/go-libp2p-kad-dht/issues/387.
-	if dht.routingTable.Size() == 0 && dht.bootstrapPeers != nil {
+	snapshotFound, bootstrapFound := 0, 0
+	if dht.routingTable.Size() == 0 && dht.rtSnapshotInterval > 0 {
+		snapshotFound = dht.connectToRTSnapshotPeers()
+	}
+
+	if dht.routingTable.Size() == 0 && snapshotFound < maxNBoostrappers && dht.bootstrapPeers != nil {
 		bootstrapPeers := dht.bootstrapPeers()
 		if len(bootstrapPeers) == 0 {
 			// No point in continuing, we have no peers!
-			return
+			return len(connected) > 0 || snapshotFound > 0
 		}
 
 		found := 0
@@ -535,17 +638,19 @@ func (dht *IpfsDHT) fixLowPeers() {
 				break
 			}
 		}
+		bootstrapFound = found
 	}
 
 	// if we still don't have peers in our routing table(probably because Identify hasn't completed),
 	// there is no point in triggering a Refresh.
 	if dht.routingTable.Size() == 0 {
-		return
+		return len(connected) > 0 || snapshotFound > 0 || bootstrapFound > 0
 	}
 
 	if dht.autoRefresh {
 		dht.rtRefreshManager.RefreshNoWait()
 	}
+	return true
 }
 
 // TODO This is hacky, horrible and the programmer needs to have his mother called a hamster.
@@ -859,6 +964,7 @@ func (dht *IpfsDHT) Close() error {
 
 	var wg sync.WaitGroup
 	closes := [...]func() error{
+		dht.saveRTSnapshot,
 		dht.rtRefreshManager.Close,
 		dht.providerStore.Close,
 	}
@@ -872,6 +978,9 @@ func (dht *IpfsDHT) Close() error {
 	}
 	wg.Wait()
 
+	if dht.rtEmptyEmitter != nil {
+		return multierr.Combine(append(errors[:], dht.rtEmptyEmitter.Close())...)
+	}
 	return multierr.Combine(errors[:]...)
 }
 
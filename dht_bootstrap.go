@@ -2,11 +2,14 @@ package dht
 
 import (
 	"context"
+	"math/rand"
 	"time"
 
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 
 	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
 )
 
 // DefaultBootstrapPeers is a set of public DHT bootstrap peers provided by libp2p.
@@ -21,6 +24,76 @@ const (
 	maxNBoostrappers          = 2
 )
 
+// Delays between attempts to bootstrap an empty routing table. The delay doubles after every failed attempt, from
+// bootstrapRetryBaseDelay up to bootstrapRetryMaxDelay.
+var (
+	bootstrapRetryBaseDelay = 5 * time.Second
+	bootstrapRetryMaxDelay  = 5 * time.Minute
+)
+
+// EvtRoutingTableEmpty is emitted on the host's event bus when the last peer is removed from the routing table, e.g.
+// because we lost connectivity. The DHT keeps trying to bootstrap on its own, applications can subscribe to it to
+// react, for instance by providing other peers to connect to.
+type EvtRoutingTableEmpty struct{}
+
+// bootstrapBackoff computes the delays between failed bootstrap attempts.
+type bootstrapBackoff struct {
+	base, max time.Duration
+	attempt   int
+}
+
+func newBootstrapBackoff() *bootstrapBackoff {
+	return &bootstrapBackoff{base: bootstrapRetryBaseDelay, max: bootstrapRetryMaxDelay}
+}
+
+// next returns the delay before the next attempt, doubling it for the one after. The delay is randomized between half
+// and all of its value so that peers which lost connectivity at the same time do not retry in lockstep.
+func (b *bootstrapBackoff) next() time.Duration {
+	d := b.base << b.attempt
+	if d <= 0 || d >= b.max {
+		d = b.max
+	} else {
+		b.attempt++
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (b *bootstrapBackoff) reset() {
+	b.attempt = 0
+}
+
+// isOnline reports whether we may be able to reach other peers. We are offline when we are not connected to anyone
+// and all our network interfaces are down, leaving us only loopback addresses to listen on, as happens when
+// a laptop is suspended or loses its network. Hosts configured to only listen on loopback addresses are never
+// offline, as the peers they can reach are local.
+func isOnline(n network.Network) bool {
+	if len(n.Peers()) > 0 {
+		return true
+	}
+
+	loopbackOnly := true
+	for _, a := range n.ListenAddresses() {
+		if !manet.IsIPLoopback(a) {
+			loopbackOnly = false
+			break
+		}
+	}
+	if loopbackOnly {
+		return true
+	}
+
+	addrs, err := n.InterfaceListenAddresses()
+	if err != nil {
+		return true
+	}
+	for _, a := range addrs {
+		if !manet.IsIPLoopback(a) {
+			return true
+		}
+	}
+	return false
+}
+
 func init() {
 	for _, s := range []string{
 		"/dnsaddr/bootstrap.libp2p.io/p2p/QmNnooDu7bfjPFoTZYxMNLWUQJyrVwtbZg5gBMjTezGAJN",
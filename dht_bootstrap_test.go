@@ -2,12 +2,19 @@ package dht
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	kb "github.com/libp2p/go-libp2p-kbucket"
 	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
+	bhost "github.com/libp2p/go-libp2p/p2p/host/basic"
+	swarmt "github.com/libp2p/go-libp2p/p2p/net/swarm/testing"
+	ma "github.com/multiformats/go-multiaddr"
 
 	"github.com/stretchr/testify/require"
 )
@@ -199,3 +206,172 @@ func TestBootstrappersReplacable(t *testing.T) {
 	require.Contains(t, d.routingTable.ListPeers(), d3.self)
 	require.Contains(t, d.routingTable.ListPeers(), d4.self)
 }
+
+func TestBootstrapBackoff(t *testing.T) {
+	oldBase, oldMax := bootstrapRetryBaseDelay, bootstrapRetryMaxDelay
+	bootstrapRetryBaseDelay, bootstrapRetryMaxDelay = time.Second, 8*time.Second
+	defer func() { bootstrapRetryBaseDelay, bootstrapRetryMaxDelay = oldBase, oldMax }()
+
+	b := newBootstrapBackoff()
+	for _, d := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second} {
+		delay := b.next()
+		require.GreaterOrEqual(t, delay, d/2)
+		require.LessOrEqual(t, delay, d)
+	}
+
+	b.reset()
+	require.LessOrEqual(t, b.next(), time.Second)
+}
+
+// offlineNetwork makes a network look like all its interfaces are down when offline is set.
+type offlineNetwork struct {
+	network.Network
+	offline atomic.Bool
+}
+
+func (n *offlineNetwork) Peers() []peer.ID {
+	if n.offline.Load() {
+		return nil
+	}
+	return n.Network.Peers()
+}
+
+func (n *offlineNetwork) ListenAddresses() []ma.Multiaddr {
+	if n.offline.Load() {
+		return []ma.Multiaddr{ma.StringCast("/ip4/0.0.0.0/tcp/4001")}
+	}
+	return n.Network.ListenAddresses()
+}
+
+func (n *offlineNetwork) InterfaceListenAddresses() ([]ma.Multiaddr, error) {
+	if n.offline.Load() {
+		return []ma.Multiaddr{ma.StringCast("/ip4/127.0.0.1/tcp/4001")}, nil
+	}
+	return n.Network.InterfaceListenAddresses()
+}
+
+type offlineHost struct {
+	host.Host
+	net *offlineNetwork
+}
+
+func (h *offlineHost) Network() network.Network {
+	return h.net
+}
+
+func TestIsOnline(t *testing.T) {
+	h, err := bhost.NewHost(swarmt.GenSwarm(t, swarmt.OptDisableReuseport), new(bhost.HostOpts))
+	require.NoError(t, err)
+	defer h.Close()
+	n := &offlineNetwork{Network: h.Network()}
+
+	// hosts only listening on loopback addresses are never offline
+	require.True(t, isOnline(n))
+
+	n.offline.Store(true)
+	require.False(t, isOnline(n))
+}
+
+func TestBootstrapPausedWhileOffline(t *testing.T) {
+	oldBase := bootstrapRetryBaseDelay
+	bootstrapRetryBaseDelay = time.Hour
+	defer func() { bootstrapRetryBaseDelay = oldBase }()
+
+	ctx := context.Background()
+	h, err := bhost.NewHost(swarmt.GenSwarm(t, swarmt.OptDisableReuseport), new(bhost.HostOpts))
+	require.NoError(t, err)
+	defer h.Close()
+	oh := &offlineHost{Host: h, net: &offlineNetwork{Network: h.Network()}}
+	oh.net.offline.Store(true)
+
+	var attempts atomic.Int32
+	d, err := New(ctx, oh,
+		testPrefix,
+		NamespacedValidator("v", blankValidator{}),
+		DisableAutoRefresh(),
+		Mode(ModeServer),
+		BootstrapPeersFunc(func() []peer.AddrInfo {
+			attempts.Add(1)
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+	defer d.Close()
+
+	em, err := h.EventBus().Emitter(new(event.EvtLocalAddressesUpdated))
+	require.NoError(t, err)
+	defer em.Close()
+
+	// no attempts while offline
+	d.fixRTIfNeeded()
+	time.Sleep(100 * time.Millisecond)
+	require.Zero(t, attempts.Load())
+
+	// an attempt as soon as we are back online
+	oh.net.offline.Store(false)
+	require.NoError(t, em.Emit(event.EvtLocalAddressesUpdated{}))
+	require.Eventually(t, func() bool { return attempts.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	// the routing table is still empty, so the next attempts are backed off
+	d.fixRTIfNeeded()
+	time.Sleep(100 * time.Millisecond)
+	require.EqualValues(t, 1, attempts.Load())
+
+	// going offline and back online resets the backoff
+	oh.net.offline.Store(true)
+	d.fixRTIfNeeded()
+	time.Sleep(100 * time.Millisecond)
+	oh.net.offline.Store(false)
+	require.NoError(t, em.Emit(event.EvtLocalAddressesUpdated{}))
+	require.Eventually(t, func() bool { return attempts.Load() == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestBootstrapRetriesWithBackoff(t *testing.T) {
+	oldBase, oldMax := bootstrapRetryBaseDelay, bootstrapRetryMaxDelay
+	bootstrapRetryBaseDelay, bootstrapRetryMaxDelay = 50*time.Millisecond, 200*time.Millisecond
+	// registered before setupDHT so that it runs once the DHT is closed
+	t.Cleanup(func() { bootstrapRetryBaseDelay, bootstrapRetryMaxDelay = oldBase, oldMax })
+
+	var (
+		mu       sync.Mutex
+		attempts []time.Time
+	)
+	setupDHT(context.Background(), t, false, BootstrapPeersFunc(func() []peer.AddrInfo {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, time.Now())
+		return nil
+	}))
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(attempts) >= 5
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	// attempts 1 to 4 come after delays of at least 25ms, 50ms, 100ms and 100ms
+	for i, min := range []time.Duration{25, 50, 100, 100} {
+		require.GreaterOrEqual(t, attempts[i+1].Sub(attempts[i]), min*time.Millisecond)
+	}
+}
+
+func TestRoutingTableEmptyEvent(t *testing.T) {
+	ctx := context.Background()
+	d1 := setupDHT(ctx, t, false)
+	d2 := setupDHT(ctx, t, false)
+
+	sub, err := d1.host.EventBus().Subscribe(new(EvtRoutingTableEmpty))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	connect(t, ctx, d1, d2)
+	d1.routingTable.RemovePeer(d2.self)
+
+	select {
+	case <-sub.Out():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a routing table empty event")
+	}
+}
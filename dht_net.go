@@ -100,6 +100,18 @@ func (dht *IpfsDHT) handleNewMessage(s network.Stream) bool {
 			metrics.ReceivedBytes.M(int64(msgLen)),
 		)
 
+		if dht.rateLimiter != nil && !dht.rateLimiter.wait(ctx, mPeer) {
+			agent := net.AgentVersion(dht.peerstore, mPeer)
+			stats.RecordWithTags(ctx,
+				[]tag.Mutator{tag.Upsert(metrics.KeyAgentVersion, agent)},
+				metrics.ThrottledMessages.M(1),
+			)
+			if n, report := dht.rateLimiter.throttledFrom(mPeer); report {
+				logger.Infow("throttled requests from peer", "from", mPeer, "agent", agent, "count", n, "type", req.GetType())
+			}
+			return false
+		}
+
 		handler := dht.handlerForMsgType(req.GetType())
 		if handler == nil {
 			stats.Record(ctx, metrics.ReceivedMessageErrors.M(1))
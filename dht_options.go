@@ -168,6 +168,9 @@ func V1ProtocolOverride(proto protocol.ID) Option {
 // The default value is 20.
 func BucketSize(bucketSize int) Option {
 	return func(c *dhtcfg.Config) error {
+		if bucketSize <= 0 {
+			return fmt.Errorf("bucket size must be positive, got %d", bucketSize)
+		}
 		c.BucketSize = bucketSize
 		return nil
 	}
@@ -178,17 +181,39 @@ func BucketSize(bucketSize int) Option {
 // The default value is 10.
 func Concurrency(alpha int) Option {
 	return func(c *dhtcfg.Config) error {
+		if alpha <= 0 {
+			return fmt.Errorf("concurrency must be positive, got %d", alpha)
+		}
 		c.Concurrency = alpha
 		return nil
 	}
 }
 
+// AdaptiveConcurrency makes every query path adjust its concurrency between min and max, starting at the configured
+// Concurrency. The concurrency of a path is raised while its requests succeed quickly and lowered when requests time
+// out or fail, so that queries make fast progress on fast networks without overloading slow ones.
+//
+// Adaptive concurrency is disabled by default.
+func AdaptiveConcurrency(min, max int) Option {
+	return func(c *dhtcfg.Config) error {
+		if min <= 0 || max < min {
+			return fmt.Errorf("adaptive concurrency bounds must satisfy 0 < min <= max, got min=%d max=%d", min, max)
+		}
+		c.AdaptiveConcurrency.Min = min
+		c.AdaptiveConcurrency.Max = max
+		return nil
+	}
+}
+
 // Resiliency configures the number of peers closest to a target that must have responded in order for a given query
 // path to complete.
 //
 // The default value is 3.
 func Resiliency(beta int) Option {
 	return func(c *dhtcfg.Config) error {
+		if beta <= 0 {
+			return fmt.Errorf("resiliency must be positive, got %d", beta)
+		}
 		c.Resiliency = beta
 		return nil
 	}
@@ -300,6 +325,22 @@ func RoutingTablePeerDiversityFilter(pg peerdiversity.PeerIPGroupFilter) Option
 	}
 }
 
+// RoutingTableSnapshotInterval configures the DHT to persist the peers in its Routing Table, along with their
+// addresses, to the datastore every interval and when the DHT is closed.
+// When the Routing Table is empty (e.g. after a restart), the DHT will first try to connect to the persisted peers
+// and will only fall back to the bootstrap peers if fewer than two of them can be reached.
+//
+// Defaults to 0, which disables persisting the Routing Table.
+func RoutingTableSnapshotInterval(interval time.Duration) Option {
+	return func(c *dhtcfg.Config) error {
+		if interval < 0 {
+			return fmt.Errorf("routing table snapshot interval must be non-negative, got %s", interval)
+		}
+		c.RoutingTable.SnapshotInterval = interval
+		return nil
+	}
+}
+
 // disableFixLowPeersRoutine disables the "fixLowPeers" routine in the DHT.
 // This is ONLY for tests.
 func disableFixLowPeersRoutine(t *testing.T) Option {
@@ -356,3 +397,57 @@ func AddressFilter(f func([]ma.Multiaddr) []ma.Multiaddr) Option {
 		return nil
 	}
 }
+
+// ServerRateLimit configures the rate at which the DHT serves inbound requests when running in server mode.
+// globalLimit is the maximum number of requests per second served across all peers, and peerLimit the maximum number
+// of requests per second served to any single remote peer. Requests exceeding a limit are briefly delayed if
+// a token will be available shortly, otherwise the stream is reset.
+//
+// Throttled requests are counted by the ThrottledMessagesView metric, labeled with the normalized agent version of the
+// remote peer. The metric is not broken down by peer ID, as any peer can make up new IDs and grow the number of label
+// values without bound. To identify abusive peers, the number of requests throttled from each peer is logged at info
+// level at most once a minute per peer instead.
+//
+// A limit of 0 disables the corresponding limiter. Defaults to 0 for both (no rate limiting): suitable limits depend
+// on the hardware and on the load the server is expected to handle, and limits enabled by default would start
+// rejecting the requests of existing deployments on upgrade.
+func ServerRateLimit(globalLimit, peerLimit float64) Option {
+	return func(c *dhtcfg.Config) error {
+		if globalLimit < 0 || peerLimit < 0 {
+			return fmt.Errorf("server rate limits must be non-negative, got global=%f peer=%f", globalLimit, peerLimit)
+		}
+		c.ServerRateLimit.Global = globalLimit
+		c.ServerRateLimit.PerPeer = peerLimit
+		return nil
+	}
+}
+
+// ValidationConcurrency configures the maximum number of records validated concurrently, both the records received
+// in PUT_VALUE requests and the ones read back from the datastore. Inbound streams wait for a free slot before their
+// record is validated.
+//
+// The default value is the number of CPUs.
+func ValidationConcurrency(n int) Option {
+	return func(c *dhtcfg.Config) error {
+		if n <= 0 {
+			return fmt.Errorf("validation concurrency must be positive, got %d", n)
+		}
+		c.ValidationConcurrency = n
+		return nil
+	}
+}
+
+// MinPutSuccesses configures the minimum number of peers that must store a record for PutValue to succeed. When fewer
+// peers do, PutValue returns an error joining the errors of the peers that failed.
+//
+// It also applies to the fullrt DHT when passed with fullrt.DHTOption. The default value is 0, PutValue then succeeds
+// as long as the record is stored locally.
+func MinPutSuccesses(n int) Option {
+	return func(c *dhtcfg.Config) error {
+		if n < 0 {
+			return fmt.Errorf("minimum put successes must be non-negative, got %d", n)
+		}
+		c.MinPutSuccesses = n
+		return nil
+	}
+}
@@ -0,0 +1,168 @@
+package dht
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	lru "github.com/hashicorp/golang-lru"
+	"golang.org/x/time/rate"
+)
+
+// maxThrottleDelay is the longest an inbound request is held back to fit within the server rate limits. Requests
+// that would have to wait longer are rejected.
+var maxThrottleDelay = 100 * time.Millisecond
+
+// peerLimitersCacheSize is the number of remote peers we keep a rate limiter for. Peers evicted from the cache start
+// over with a full burst, which is fine as the global limiter still bounds the total rate.
+const peerLimitersCacheSize = 1024
+
+// throttleReportInterval is how often at most the requests throttled from a peer are reported in the logs.
+var throttleReportInterval = time.Minute
+
+// throttledPeersCacheSize is the number of remote peers we count throttled requests for between two reports.
+const throttledPeersCacheSize = 1024
+
+type throttleCount struct {
+	n          int
+	lastReport time.Time
+}
+
+// serverRateLimiter limits the rate of inbound requests, both across all peers and per remote peer.
+type serverRateLimiter struct {
+	global *rate.Limiter
+
+	peerLimit rate.Limit
+	peerBurst int
+	peersLk   sync.Mutex
+	peers     *lru.Cache // peer.ID -> *rate.Limiter
+
+	throttledLk sync.Mutex
+	throttled   *lru.Cache // peer.ID -> *throttleCount
+}
+
+// newServerRateLimiter returns a rate limiter for the given requests per second, where a limit of 0 disables the
+// corresponding limiter. It returns nil if both limits are disabled.
+func newServerRateLimiter(globalLimit, peerLimit float64) (*serverRateLimiter, error) {
+	if globalLimit == 0 && peerLimit == 0 {
+		return nil, nil
+	}
+
+	throttled, err := lru.New(throttledPeersCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	l := &serverRateLimiter{throttled: throttled}
+	if globalLimit > 0 {
+		l.global = rate.NewLimiter(rate.Limit(globalLimit), burstFor(globalLimit))
+	}
+	if peerLimit > 0 {
+		peers, err := lru.New(peerLimitersCacheSize)
+		if err != nil {
+			return nil, err
+		}
+		l.peers = peers
+		l.peerLimit = rate.Limit(peerLimit)
+		l.peerBurst = burstFor(peerLimit)
+	}
+	return l, nil
+}
+
+// burstFor allows a second worth of requests to be served at once.
+func burstFor(limit float64) int {
+	return int(math.Max(1, math.Ceil(limit)))
+}
+
+func (l *serverRateLimiter) peerLimiter(p peer.ID) *rate.Limiter {
+	l.peersLk.Lock()
+	defer l.peersLk.Unlock()
+
+	if v, ok := l.peers.Get(p); ok {
+		return v.(*rate.Limiter)
+	}
+	pl := rate.NewLimiter(l.peerLimit, l.peerBurst)
+	l.peers.Add(p, pl)
+	return pl
+}
+
+// throttledFrom records that a request from p was throttled. It returns the number of requests throttled from p since
+// they were last reported, and whether they should be reported now, which happens at most every
+// throttleReportInterval per peer so that operators can spot abusive peers without flooding the logs.
+func (l *serverRateLimiter) throttledFrom(p peer.ID) (int, bool) {
+	l.throttledLk.Lock()
+	defer l.throttledLk.Unlock()
+
+	var c *throttleCount
+	if v, ok := l.throttled.Get(p); ok {
+		c = v.(*throttleCount)
+	} else {
+		c = &throttleCount{}
+		l.throttled.Add(p, c)
+	}
+	c.n++
+	if time.Since(c.lastReport) < throttleReportInterval {
+		return c.n, false
+	}
+	n := c.n
+	c.n = 0
+	c.lastReport = time.Now()
+	return n, true
+}
+
+// wait blocks until a request from p can be served and returns true, or returns false straight away if the request
+// would have to wait longer than maxThrottleDelay.
+//
+// The peer limit is checked first so that a single aggressive peer is throttled by its own limiter and cannot
+// consume the global budget.
+func (l *serverRateLimiter) wait(ctx context.Context, p peer.ID) bool {
+	limiters := make([]*rate.Limiter, 0, 2)
+	if l.peers != nil {
+		limiters = append(limiters, l.peerLimiter(p))
+	}
+	if l.global != nil {
+		limiters = append(limiters, l.global)
+	}
+
+	now := time.Now()
+	reservations := make([]*rate.Reservation, 0, len(limiters))
+	cancel := func() {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+
+	var delay time.Duration
+	for _, lim := range limiters {
+		r := lim.ReserveN(now, 1)
+		if !r.OK() {
+			cancel()
+			return false
+		}
+		reservations = append(reservations, r)
+
+		if d := r.DelayFrom(now); d > delay {
+			delay = d
+		}
+		if delay > maxThrottleDelay {
+			cancel()
+			return false
+		}
+	}
+
+	if delay == 0 {
+		return true
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		cancel()
+		return false
+	}
+}
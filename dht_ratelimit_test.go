@@ -0,0 +1,145 @@
+package dht
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-kad-dht/internal/net"
+	"github.com/libp2p/go-libp2p-kad-dht/metrics"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+func TestServerRateLimiterDisabled(t *testing.T) {
+	l, err := newServerRateLimiter(0, 0)
+	require.NoError(t, err)
+	require.Nil(t, l)
+}
+
+func TestServerRateLimiterPerPeer(t *testing.T) {
+	ctx := context.Background()
+	l, err := newServerRateLimiter(0, 2)
+	require.NoError(t, err)
+
+	a, b := peer.ID("a"), peer.ID("b")
+	require.True(t, l.wait(ctx, a))
+	require.True(t, l.wait(ctx, a))
+	// the next token for a is 500ms away, too long to wait for
+	require.False(t, l.wait(ctx, a))
+
+	// other peers are not affected
+	require.True(t, l.wait(ctx, b))
+	require.True(t, l.wait(ctx, b))
+}
+
+func TestServerRateLimiterGlobal(t *testing.T) {
+	ctx := context.Background()
+	l, err := newServerRateLimiter(3, 0)
+	require.NoError(t, err)
+
+	for _, p := range []peer.ID{"a", "b", "c"} {
+		require.True(t, l.wait(ctx, p))
+	}
+	require.False(t, l.wait(ctx, "d"))
+}
+
+func TestServerRateLimiterAggressivePeerKeepsGlobalBudget(t *testing.T) {
+	ctx := context.Background()
+	l, err := newServerRateLimiter(5, 2)
+	require.NoError(t, err)
+
+	served := 0
+	for i := 0; i < 20; i++ {
+		if l.wait(ctx, "aggressive") {
+			served++
+		}
+	}
+	require.Equal(t, 2, served)
+
+	// requests rejected by the peer limiter must not have used up global tokens
+	require.True(t, l.wait(ctx, "b"))
+	require.True(t, l.wait(ctx, "b"))
+	require.True(t, l.wait(ctx, "c"))
+	require.False(t, l.wait(ctx, "c"))
+}
+
+func TestServerRateLimiterDelaysShortly(t *testing.T) {
+	ctx := context.Background()
+	l, err := newServerRateLimiter(0, 20)
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		require.True(t, l.wait(ctx, "a"))
+	}
+
+	// the next token is 50ms away, which is within maxThrottleDelay
+	start := time.Now()
+	require.True(t, l.wait(ctx, "a"))
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestServerRateLimitResetsStreams(t *testing.T) {
+	require.NoError(t, view.Register(metrics.ThrottledMessagesView))
+	defer view.Unregister(metrics.ThrottledMessagesView)
+
+	ctx := context.Background()
+	server := setupDHT(ctx, t, false, ServerRateLimit(0, 5))
+	client := setupDHT(ctx, t, true)
+	connectNoSync(t, ctx, client, server)
+	wait(t, ctx, client, server)
+
+	// the server allows a burst of 5 requests from the client, refilled every
+	// 200ms, so sending many more back to back must get some of them rejected.
+	var served, failed int
+	for i := 0; i < 20; i++ {
+		if _, err := client.protoMessenger.GetClosestPeers(ctx, server.self, client.self); err != nil {
+			failed++
+		} else {
+			served++
+		}
+	}
+	require.NotZero(t, served)
+	require.NotZero(t, failed)
+
+	// the throttled requests are attributed to the agent of the client
+	rows, err := view.RetrieveData(metrics.ThrottledMessagesView.Name)
+	require.NoError(t, err)
+	require.NotEmpty(t, rows)
+	agent := net.AgentVersion(server.peerstore, client.self)
+	for _, row := range rows {
+		require.Contains(t, row.Tags, tag.Tag{Key: metrics.KeyAgentVersion, Value: agent})
+	}
+}
+
+func TestServerRateLimiterReportsThrottledPeers(t *testing.T) {
+	old := throttleReportInterval
+	throttleReportInterval = 50 * time.Millisecond
+	defer func() { throttleReportInterval = old }()
+
+	l, err := newServerRateLimiter(0, 1)
+	require.NoError(t, err)
+
+	// the first throttled request of a peer is reported straight away
+	n, report := l.throttledFrom("a")
+	require.True(t, report)
+	require.Equal(t, 1, n)
+
+	// the next ones are counted until the next report
+	for i := 1; i <= 3; i++ {
+		n, report = l.throttledFrom("a")
+		require.False(t, report)
+		require.Equal(t, i, n)
+	}
+	n, report = l.throttledFrom("b")
+	require.True(t, report)
+	require.Equal(t, 1, n)
+
+	time.Sleep(2 * throttleReportInterval)
+	n, report = l.throttledFrom("a")
+	require.True(t, report)
+	require.Equal(t, 4, n)
+}
@@ -39,7 +39,9 @@ import (
 	detectrace "github.com/ipfs/go-detect-race"
 	kb "github.com/libp2p/go-libp2p-kbucket"
 	record "github.com/libp2p/go-libp2p-record"
+	recpb "github.com/libp2p/go-libp2p-record/pb"
 	bhost "github.com/libp2p/go-libp2p/p2p/host/basic"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
 	swarmt "github.com/libp2p/go-libp2p/p2p/net/swarm/testing"
 )
 
@@ -336,6 +338,62 @@ func TestValueGetSet(t *testing.T) {
 	}
 }
 
+func TestValuePutOffline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d1 := setupDHT(ctx, t, false)
+	d2 := setupDHT(ctx, t, false)
+	connect(t, ctx, d1, d2)
+
+	err := d1.PutValue(ctx, "/v/hello", []byte("world"), routing.Offline)
+	require.NoError(t, err)
+
+	// the record is only stored locally
+	val, err := d1.GetValue(ctx, "/v/hello", routing.Offline)
+	require.NoError(t, err)
+	require.Equal(t, []byte("world"), val)
+
+	rec, err := d2.getLocal(ctx, "/v/hello")
+	require.NoError(t, err)
+	require.Nil(t, rec)
+}
+
+func TestValuePutMinSuccesses(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// only the DHTs with a validator for /w accept the record
+	put := func(t *testing.T, min int, accepting int) error {
+		d := setupDHT(ctx, t, false, NamespacedValidator("w", blankValidator{}), MinPutSuccesses(min))
+		for i := 0; i < 3; i++ {
+			var o *IpfsDHT
+			if i < accepting {
+				o = setupDHT(ctx, t, false, NamespacedValidator("w", blankValidator{}))
+			} else {
+				o = setupDHT(ctx, t, false)
+			}
+			connect(t, ctx, d, o)
+		}
+		return d.PutValue(ctx, "/w/hello", []byte("world"))
+	}
+
+	t.Run("no minimum", func(t *testing.T) {
+		require.NoError(t, put(t, 0, 0))
+	})
+	t.Run("minimum met", func(t *testing.T) {
+		require.NoError(t, put(t, 1, 1))
+	})
+	t.Run("minimum not met", func(t *testing.T) {
+		err := put(t, 1, 0)
+		require.Error(t, err)
+		// the error of every peer that failed is reported
+		var joined interface{ Unwrap() []error }
+		require.ErrorAs(t, err, &joined)
+		require.Len(t, joined.Unwrap(), 3)
+	})
+}
+
 func TestValueSetInvalid(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -2074,6 +2132,38 @@ func TestInvalidKeys(t *testing.T) {
 	}
 }
 
+func TestInvalidOptions(t *testing.T) {
+	ctx := context.Background()
+
+	h, err := bhost.NewHost(swarmt.GenSwarm(t, swarmt.OptDisableReuseport), new(bhost.HostOpts))
+	require.NoError(t, err)
+	h.Start()
+	defer h.Close()
+
+	for name, opts := range map[string][]Option{
+		"zero bucket size":       {BucketSize(0)},
+		"negative concurrency":   {Concurrency(-1)},
+		"zero resiliency":        {Resiliency(0)},
+		"nil validator":          {testPrefix, Validator(nil)},
+		"default prefix bucket":  {BucketSize(10)},
+		"negative rate limit":    {ServerRateLimit(-1, 0)},
+		"zero validation slots":  {ValidationConcurrency(0)},
+		"inverted adaptive":      {AdaptiveConcurrency(5, 2)},
+		"negative snapshot time": {RoutingTableSnapshotInterval(-time.Second)},
+		"negative put successes": {MinPutSuccesses(-1)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(ctx, h, opts...)
+			require.Error(t, err)
+		})
+	}
+
+	// a nil validator is fine if values are disabled
+	d, err := New(ctx, h, testPrefix, Validator(nil), DisableValues())
+	require.NoError(t, err)
+	require.NoError(t, d.Close())
+}
+
 func TestV1ProtocolOverride(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -2269,6 +2359,7 @@ func TestBootstrapPeersFunc(t *testing.T) {
 
 	dhtB := setupDHT(ctx, t, false, BootstrapPeersFunc(bootstrapFuncB))
 	require.Equal(t, 0, len(dhtB.host.Network().Peers()))
+	require.False(t, dhtB.fixLowPeers())
 
 	addrA := peer.AddrInfo{
 		ID:    dhtA.self,
@@ -2279,7 +2370,7 @@ func TestBootstrapPeersFunc(t *testing.T) {
 	bootstrapPeersB = []peer.AddrInfo{addrA}
 	lock.Unlock()
 
-	dhtB.fixLowPeers()
+	require.True(t, dhtB.fixLowPeers())
 	require.NotEqual(t, 0, len(dhtB.host.Network().Peers()))
 }
 
@@ -2424,3 +2515,99 @@ func TestAddrFilter(t *testing.T) {
 	}
 	require.Equal(t, len(publicAddrs)+len(privAddrs), len(d3.host.Peerstore().Addrs(peerid)))
 }
+
+func setupPutValuesTest(tb testing.TB, ctx context.Context, latency time.Duration) (client, server *IpfsDHT) {
+	mn := mocknet.New()
+	tb.Cleanup(func() { mn.Close() })
+	mn.SetLinkDefaults(mocknet.LinkOptions{Latency: latency})
+
+	dhts := make([]*IpfsDHT, 2)
+	for i := range dhts {
+		h, err := mn.GenPeer()
+		require.NoError(tb, err)
+		d, err := New(ctx, h, testPrefix, NamespacedValidator("v", blankValidator{}), DisableAutoRefresh(), Mode(ModeServer))
+		require.NoError(tb, err)
+		tb.Cleanup(func() { d.Close() })
+		dhts[i] = d
+	}
+	require.NoError(tb, mn.LinkAll())
+	_, err := mn.ConnectPeers(dhts[0].self, dhts[1].self)
+	require.NoError(tb, err)
+	return dhts[0], dhts[1]
+}
+
+func putValuesRecords(n int) []*recpb.Record {
+	recs := make([]*recpb.Record, n)
+	for i := range recs {
+		recs[i] = record.MakePutRecord(fmt.Sprintf("/v/%d", i), []byte(fmt.Sprintf("value %d", i)))
+	}
+	return recs
+}
+
+func TestPutValues(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client, server := setupPutValuesTest(t, ctx, 0)
+
+	recs := putValuesRecords(20)
+	for _, err := range client.protoMessenger.PutValues(ctx, server.self, recs) {
+		require.NoError(t, err)
+	}
+	for _, rec := range recs {
+		stored, err := server.getLocal(ctx, string(rec.Key))
+		require.NoError(t, err)
+		require.Equal(t, rec.Value, stored.Value)
+	}
+}
+
+func TestPutValuesRejectedRecord(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client, server := setupPutValuesTest(t, ctx, 0)
+
+	// the server has no validator for this namespace and rejects the record, resetting the stream
+	recs := putValuesRecords(10)
+	recs[3] = record.MakePutRecord("/unknown/3", []byte("value 3"))
+
+	errs := client.protoMessenger.PutValues(ctx, server.self, recs)
+	for i, rec := range recs {
+		if i == 3 {
+			require.Error(t, errs[i])
+			continue
+		}
+		require.NoError(t, errs[i], i)
+		stored, err := server.getLocal(ctx, string(rec.Key))
+		require.NoError(t, err)
+		require.Equal(t, rec.Value, stored.Value)
+	}
+}
+
+// BenchmarkPutValues measures storing 100 records on a peer one request after the other, and pipelined on a single
+// stream.
+func BenchmarkPutValues(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client, server := setupPutValuesTest(b, ctx, 5*time.Millisecond)
+	recs := putValuesRecords(100)
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, rec := range recs {
+				if err := client.protoMessenger.PutValue(ctx, server.self, rec); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		b.ReportMetric(float64(b.N*len(recs))/b.Elapsed().Seconds(), "records/s")
+	})
+	b.Run("pipelined", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, err := range client.protoMessenger.PutValues(ctx, server.self, recs) {
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		b.ReportMetric(float64(b.N*len(recs))/b.Elapsed().Seconds(), "records/s")
+	})
+}
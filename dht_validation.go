@@ -0,0 +1,90 @@
+package dht
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+
+	record "github.com/libp2p/go-libp2p-record"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// validationCacheTTL is how long a successful validation of some record bytes is remembered. It is kept short so
+// that records expiring in the meantime are only accepted and served for a little while.
+var validationCacheTTL = 30 * time.Second
+
+// validationCacheSize is the number of recently validated records we remember.
+const validationCacheSize = 1024
+
+// recordValidator validates records received in PUT_VALUE requests and records read back from the datastore. It
+// bounds the number of validations running at once, so that bursts of puts cannot grab all the CPU, and skips
+// validating the same record bytes again when a publisher sends them to many peers through us.
+//
+// Each stream is served by its own goroutine, which blocks in validate until a slot is free, so requests of
+// a stream are still handled in order and streams are backpressured while all slots are in use.
+type recordValidator struct {
+	slots chan struct{}
+	cache *lru.Cache // [sha256.Size]byte -> time.Time
+}
+
+func newRecordValidator(concurrency int) (*recordValidator, error) {
+	cache, err := lru.New(validationCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &recordValidator{
+		slots: make(chan struct{}, concurrency),
+		cache: cache,
+	}, nil
+}
+
+// validate checks the record value stored under key with v, the validator currently set on the DHT.
+func (rv *recordValidator) validate(ctx context.Context, v record.Validator, key string, value []byte) error {
+	h := recordHash(key, value)
+	if rv.validatedRecently(h) {
+		return nil
+	}
+
+	select {
+	case rv.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	err := v.Validate(key, value)
+	<-rv.slots
+	if err != nil {
+		return err
+	}
+
+	rv.cache.Add(h, time.Now())
+	return nil
+}
+
+func (rv *recordValidator) validatedRecently(h [sha256.Size]byte) bool {
+	v, ok := rv.cache.Get(h)
+	if !ok {
+		return false
+	}
+	if time.Since(v.(time.Time)) > validationCacheTTL {
+		rv.cache.Remove(h)
+		return false
+	}
+	return true
+}
+
+// recordHash identifies a record by both its key and value, as the key is part of what is being validated.
+func recordHash(key string, value []byte) [sha256.Size]byte {
+	var l [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(l[:], uint64(len(key)))
+
+	s := sha256.New()
+	s.Write(l[:n])
+	s.Write([]byte(key))
+	s.Write(value)
+
+	var h [sha256.Size]byte
+	s.Sum(h[:0])
+	return h
+}
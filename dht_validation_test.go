@@ -0,0 +1,115 @@
+package dht
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingValidator struct {
+	calls   atomic.Int32
+	block   chan struct{}
+	running atomic.Int32
+	maxRun  atomic.Int32
+}
+
+func (v *countingValidator) Validate(key string, value []byte) error {
+	v.calls.Add(1)
+	n := v.running.Add(1)
+	defer v.running.Add(-1)
+	for {
+		m := v.maxRun.Load()
+		if n <= m || v.maxRun.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	if v.block != nil {
+		<-v.block
+	}
+	if string(value) == "invalid" {
+		return errors.New("invalid record")
+	}
+	return nil
+}
+
+func (v *countingValidator) Select(key string, values [][]byte) (int, error) {
+	return 0, nil
+}
+
+func TestRecordValidatorCachesSuccesses(t *testing.T) {
+	ctx := context.Background()
+	rv, err := newRecordValidator(1)
+	require.NoError(t, err)
+	v := &countingValidator{}
+
+	require.NoError(t, rv.validate(ctx, v, "/v/a", []byte("value")))
+	require.NoError(t, rv.validate(ctx, v, "/v/a", []byte("value")))
+	require.EqualValues(t, 1, v.calls.Load())
+
+	// the same value under another key is validated again
+	require.NoError(t, rv.validate(ctx, v, "/v/b", []byte("value")))
+	require.EqualValues(t, 2, v.calls.Load())
+
+	// failures are not cached
+	require.Error(t, rv.validate(ctx, v, "/v/a", []byte("invalid")))
+	require.Error(t, rv.validate(ctx, v, "/v/a", []byte("invalid")))
+	require.EqualValues(t, 4, v.calls.Load())
+}
+
+func TestRecordValidatorCacheExpires(t *testing.T) {
+	old := validationCacheTTL
+	validationCacheTTL = 10 * time.Millisecond
+	defer func() { validationCacheTTL = old }()
+
+	ctx := context.Background()
+	rv, err := newRecordValidator(1)
+	require.NoError(t, err)
+	v := &countingValidator{}
+
+	require.NoError(t, rv.validate(ctx, v, "/v/a", []byte("value")))
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, rv.validate(ctx, v, "/v/a", []byte("value")))
+	require.EqualValues(t, 2, v.calls.Load())
+}
+
+func TestRecordValidatorBoundsConcurrency(t *testing.T) {
+	ctx := context.Background()
+	rv, err := newRecordValidator(2)
+	require.NoError(t, err)
+	v := &countingValidator{block: make(chan struct{})}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, rv.validate(ctx, v, "/v/a", []byte{byte(i)}))
+		}(i)
+	}
+
+	require.Eventually(t, func() bool { return v.running.Load() == 2 }, time.Second, time.Millisecond)
+	close(v.block)
+	wg.Wait()
+	require.EqualValues(t, 2, v.maxRun.Load())
+	require.EqualValues(t, 10, v.calls.Load())
+}
+
+func TestRecordValidatorContextCancelled(t *testing.T) {
+	rv, err := newRecordValidator(1)
+	require.NoError(t, err)
+	v := &countingValidator{block: make(chan struct{})}
+	defer close(v.block)
+
+	go func() { _ = rv.validate(context.Background(), v, "/v/a", []byte("busy")) }()
+	require.Eventually(t, func() bool { return v.running.Load() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, rv.validate(ctx, v, "/v/b", []byte("value")), context.DeadlineExceeded)
+}
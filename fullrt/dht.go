@@ -95,6 +95,8 @@ type FullRT struct {
 	waitFrac     float64
 	timeoutPerOp time.Duration
 
+	minPutSuccesses int
+
 	bulkSendParallelism int
 
 	self peer.ID
@@ -193,6 +195,8 @@ func NewFullRT(h host.Host, protocolPrefix protocol.ID, options ...Option) (*Ful
 		waitFrac:     fullrtcfg.waitFrac,
 		timeoutPerOp: fullrtcfg.timeoutPerOp,
 
+		minPutSuccesses: dhtcfg.MinPutSuccesses,
+
 		crawlerInterval: fullrtcfg.crawlInterval,
 
 		bulkSendParallelism: fullrtcfg.bulkSendParallelism,
@@ -470,6 +474,11 @@ func (dht *FullRT) PutValue(ctx context.Context, key string, value []byte, opts
 		return routing.ErrNotSupported
 	}
 
+	var cfg routing.Options
+	if err := cfg.Apply(opts...); err != nil {
+		return err
+	}
+
 	logger.Debugw("putting value", "key", internal.LoggableRecordKeyString(key))
 
 	// don't even allow local users to put bad values.
@@ -502,20 +511,39 @@ func (dht *FullRT) PutValue(ctx context.Context, key string, value []byte, opts
 		return err
 	}
 
+	// an offline put only stores the record locally
+	if cfg.Offline {
+		return nil
+	}
+
 	peers, err := dht.GetClosestPeers(ctx, key)
 	if err != nil {
 		return err
 	}
 
+	var (
+		mu     sync.Mutex
+		failed []error
+	)
 	successes := dht.execOnMany(ctx, func(ctx context.Context, p peer.ID) error {
 		routing.PublishQueryEvent(ctx, &routing.QueryEvent{
 			Type: routing.Value,
 			ID:   p,
 		})
 		err := dht.protoMessenger.PutValue(ctx, p, rec)
+		if err != nil {
+			mu.Lock()
+			failed = append(failed, fmt.Errorf("peer %s: %w", p, err))
+			mu.Unlock()
+		}
 		return err
 	}, peers, true)
 
+	if successes < dht.minPutSuccesses {
+		mu.Lock()
+		defer mu.Unlock()
+		return fmt.Errorf("put value to %d peers, want at least %d: %w", successes, dht.minPutSuccesses, errors.Join(failed...))
+	}
 	if successes == 0 {
 		return fmt.Errorf("failed to complete put")
 	}
@@ -973,7 +1001,7 @@ func (dht *FullRT) ProvideMany(ctx context.Context, keys []multihash.Multihash)
 		keysAsPeerIDs = append(keysAsPeerIDs, peer.ID(k))
 	}
 
-	return dht.bulkMessageSend(ctx, keysAsPeerIDs, fn, true)
+	return dht.bulkMessageSend(ctx, keysAsPeerIDs, fn, nil, true)
 }
 
 func (dht *FullRT) PutMany(ctx context.Context, keys []string, values [][]byte) error {
@@ -999,15 +1027,22 @@ func (dht *FullRT) PutMany(ctx context.Context, keys []string, values [][]byte)
 		return fmt.Errorf("does not support duplicate keys")
 	}
 
-	fn := func(ctx context.Context, p, k peer.ID) error {
-		keyStr := string(k)
-		return dht.protoMessenger.PutValue(ctx, p, record.MakePutRecord(keyStr, keyRecMap[keyStr]))
+	// pipeline the records going to the same peer on a single stream
+	batchFn := func(ctx context.Context, p peer.ID, ks []peer.ID) []error {
+		recs := make([]*recpb.Record, len(ks))
+		for i, k := range ks {
+			keyStr := string(k)
+			recs[i] = record.MakePutRecord(keyStr, keyRecMap[keyStr])
+		}
+		return dht.protoMessenger.PutValues(ctx, p, recs)
 	}
 
-	return dht.bulkMessageSend(ctx, keysAsPeerIDs, fn, false)
+	return dht.bulkMessageSend(ctx, keysAsPeerIDs, nil, batchFn, false)
 }
 
-func (dht *FullRT) bulkMessageSend(ctx context.Context, keys []peer.ID, fn func(ctx context.Context, target, k peer.ID) error, isProvRec bool) error {
+// bulkMessageSend sends the messages for keys to their closest peers. fn sends the message for a single key, batchFn,
+// if not nil, is used instead to send all the messages to a peer at once and returns an error per key.
+func (dht *FullRT) bulkMessageSend(ctx context.Context, keys []peer.ID, fn func(ctx context.Context, target, k peer.ID) error, batchFn func(ctx context.Context, target peer.ID, ks []peer.ID) []error, isProvRec bool) error {
 	ctx, span := internal.StartSpan(ctx, "FullRT.BulkMessageSend")
 	defer span.End()
 
@@ -1075,6 +1110,47 @@ func (dht *FullRT) bulkMessageSend(ctx context.Context, keys []peer.ID, fn func(
 				}
 				dialCancel()
 				dht.h.ConnManager().Protect(p, connmgrTag)
+				if batchFn != nil {
+					// send all the keys that still need it at once, giving the batch as much time as sending them one by
+					// one would
+					sendKeys := make([]peer.ID, 0, len(workKeys))
+					var batchTimeout time.Duration
+					for _, k := range workKeys {
+						keyReport := keySuccesses[k]
+						keyReport.mx.RLock()
+						if keyReport.successes < numSuccessfulToWaitFor {
+							sendKeys = append(sendKeys, k)
+							batchTimeout += dht.timeoutPerOp
+						} else if time.Since(keyReport.lastSuccess) <= time.Millisecond*500 {
+							sendKeys = append(sendKeys, k)
+							batchTimeout += time.Millisecond * 500
+						}
+						keyReport.mx.RUnlock()
+					}
+					if len(sendKeys) == 0 {
+						dht.h.ConnManager().Unprotect(p, connmgrTag)
+						continue
+					}
+
+					fnCtx, fnCancel := context.WithTimeout(ctx, batchTimeout)
+					for i, err := range batchFn(fnCtx, p, sendKeys) {
+						keyReport := keySuccesses[sendKeys[i]]
+						keyReport.mx.Lock()
+						if err == nil {
+							keyReport.successes++
+							if keyReport.successes >= numSuccessfulToWaitFor {
+								keyReport.lastSuccess = time.Now()
+							}
+						} else {
+							keyReport.failures++
+						}
+						keyReport.mx.Unlock()
+					}
+					fnCancel()
+
+					dht.h.ConnManager().Unprotect(p, connmgrTag)
+					continue
+				}
 				for _, k := range workKeys {
 					keyReport := keySuccesses[k]
 
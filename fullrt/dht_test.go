@@ -1,10 +1,17 @@
 package fullrt
 
 import (
+	"context"
 	"strconv"
 	"testing"
 
+	kaddht "github.com/libp2p/go-libp2p-kad-dht"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
+	bhost "github.com/libp2p/go-libp2p/p2p/host/basic"
+	swarmt "github.com/libp2p/go-libp2p/p2p/net/swarm/testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestDivideByChunkSize(t *testing.T) {
@@ -84,3 +91,31 @@ func TestDivideByChunkSize(t *testing.T) {
 		}
 	})
 }
+
+type blankValidator struct{}
+
+func (blankValidator) Validate(_ string, _ []byte) error        { return nil }
+func (blankValidator) Select(_ string, _ [][]byte) (int, error) { return 0, nil }
+
+func TestPutValueOffline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h, err := bhost.NewHost(swarmt.GenSwarm(t, swarmt.OptDisableReuseport), new(bhost.HostOpts))
+	require.NoError(t, err)
+	h.Start()
+	defer h.Close()
+
+	d, err := NewFullRT(h, "/test", DHTOption(
+		kaddht.NamespacedValidator("v", blankValidator{}),
+		kaddht.BootstrapPeers(),
+	))
+	require.NoError(t, err)
+	defer d.Close()
+
+	// the record is only stored locally, without looking for peers to store it on
+	require.NoError(t, d.PutValue(ctx, "/v/hello", []byte("world"), routing.Offline))
+	rec, err := d.getLocal(ctx, "/v/hello")
+	require.NoError(t, err)
+	require.Equal(t, []byte("world"), rec.GetValue())
+}
@@ -35,6 +35,7 @@ require (
 	go.opentelemetry.io/otel/trace v1.16.0
 	go.uber.org/multierr v1.11.0
 	go.uber.org/zap v1.25.0
+	golang.org/x/time v0.3.0
 	gonum.org/v1/gonum v0.13.0
 )
 
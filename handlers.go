@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
 	pstore "github.com/libp2p/go-libp2p/p2p/host/peerstore"
 
@@ -15,6 +16,7 @@ import (
 	ds "github.com/ipfs/go-datastore"
 	"github.com/libp2p/go-libp2p-kad-dht/internal"
 	pb "github.com/libp2p/go-libp2p-kad-dht/pb"
+	record "github.com/libp2p/go-libp2p-record"
 	recpb "github.com/libp2p/go-libp2p-record/pb"
 	"github.com/multiformats/go-base32"
 )
@@ -65,6 +67,9 @@ func (dht *IpfsDHT) handleGetValue(ctx context.Context, p peer.ID, pmes *pb.Mess
 	if err != nil {
 		return nil, err
 	}
+	if rec == nil {
+		rec = dht.checkLocalPublicKey(k)
+	}
 	resp.Record = rec
 
 	// Find closest peer on given cluster to desired key and reply with that info
@@ -142,6 +147,31 @@ func (dht *IpfsDHT) checkLocalDatastore(ctx context.Context, k []byte) (*recpb.R
 	return rec, nil
 }
 
+// checkLocalPublicKey answers public key requests from the peerstore, which knows our own key, the keys of the peers
+// we've interacted with and the keys inlined in peer IDs. This way we don't need a stored record to answer them.
+// It returns nil if k is not a public key record key or we don't know the key.
+func (dht *IpfsDHT) checkLocalPublicKey(k []byte) *recpb.Record {
+	ns, rest, err := record.SplitKey(string(k))
+	if err != nil || ns != "pk" {
+		return nil
+	}
+	p, err := peer.IDFromBytes([]byte(rest))
+	if err != nil {
+		return nil
+	}
+
+	pk := dht.peerstore.PubKey(p)
+	if pk == nil {
+		return nil
+	}
+	pkb, err := crypto.MarshalPublicKey(pk)
+	if err != nil {
+		logger.Debugw("failed to marshal public key", "peer", p, "error", err)
+		return nil
+	}
+	return record.MakePutRecord(string(k), pkb)
+}
+
 // Cleans the record (to avoid storing arbitrary data).
 func cleanRecord(rec *recpb.Record) {
 	rec.TimeReceived = ""
@@ -166,7 +196,7 @@ func (dht *IpfsDHT) handlePutValue(ctx context.Context, p peer.ID, pmes *pb.Mess
 	cleanRecord(rec)
 
 	// Make sure the record is valid (not expired, valid signature etc)
-	if err = dht.Validator.Validate(string(rec.GetKey()), rec.GetValue()); err != nil {
+	if err = dht.recordValidator.validate(ctx, dht.Validator, string(rec.GetKey()), rec.GetValue()); err != nil {
 		logger.Infow("bad dht record in PUT", "from", p, "key", internal.LoggableRecordKeyBytes(rec.GetKey()), "error", err)
 		return nil, err
 	}
@@ -236,8 +266,11 @@ func (dht *IpfsDHT) getRecordFromDatastore(ctx context.Context, dskey ds.Key) (*
 		return nil, nil
 	}
 
-	err = dht.Validator.Validate(string(rec.GetKey()), rec.GetValue())
+	err = dht.recordValidator.validate(ctx, dht.Validator, string(rec.GetKey()), rec.GetValue())
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		// Invalid record in datastore, probably expired but don't return an error,
 		// we'll just overwrite it
 		logger.Debugw("local record verify failed", "key", rec.GetKey(), "error", err)
@@ -5,10 +5,12 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	proto "github.com/gogo/protobuf/proto"
+	"github.com/ipfs/boxo/ipns"
 	"github.com/libp2p/go-libp2p"
 	pb "github.com/libp2p/go-libp2p-kad-dht/pb"
 	recpb "github.com/libp2p/go-libp2p-record/pb"
@@ -139,3 +141,80 @@ func BenchmarkHandleFindPeer(b *testing.B) {
 	}
 
 }
+
+// BenchmarkHandlePutValue measures how fast we accept ed25519 signed IPNS records from several concurrent streams.
+// Publishers sending the same records again, e.g. to reach more peers through us, hit the validation cache.
+func BenchmarkHandlePutValue(b *testing.B) {
+	for _, tc := range []struct {
+		name    string
+		records int
+	}{
+		{"unique", 0},
+		{"repeated", 16},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			h, err := libp2p.New()
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer h.Close()
+
+			d, err := New(ctx, h)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer d.Close()
+
+			n := tc.records
+			if n == 0 {
+				n = b.N
+			}
+			reqs := make([]*pb.Message, n)
+			rng := rand.New(rand.NewSource(150))
+			for i := range reqs {
+				reqs[i] = makeIpnsPut(b, rng)
+			}
+
+			var next atomic.Int64
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(p *testing.PB) {
+				for p.Next() {
+					i := next.Add(1) - 1
+					// handlePutValue modifies the record, so hand it a copy
+					req := proto.Clone(reqs[int(i)%len(reqs)]).(*pb.Message)
+					if _, err := d.handlePutValue(ctx, "testpeer", req); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func makeIpnsPut(b *testing.B, rng *rand.Rand) *pb.Message {
+	sk, pk, err := crypto.GenerateEd25519Key(rng)
+	if err != nil {
+		b.Fatal(err)
+	}
+	id, err := peer.IDFromPublicKey(pk)
+	if err != nil {
+		b.Fatal(err)
+	}
+	entry, err := ipns.Create(sk, []byte("/ipfs/bafkqaaa"), 1, time.Now().Add(time.Hour), time.Minute)
+	if err != nil {
+		b.Fatal(err)
+	}
+	value, err := proto.Marshal(entry)
+	if err != nil {
+		b.Fatal(err)
+	}
+	key := []byte(ipns.RecordKey(id))
+	return &pb.Message{
+		Type:   pb.Message_PUT_VALUE,
+		Key:    key,
+		Record: &recpb.Record{Key: key, Value: value},
+	}
+}
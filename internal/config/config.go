@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"runtime"
 	"time"
 
 	"github.com/ipfs/boxo/ipns"
@@ -57,11 +58,24 @@ type Config struct {
 		CheckInterval       time.Duration
 		PeerFilter          RouteTableFilterFunc
 		DiversityFilter     peerdiversity.PeerIPGroupFilter
+		SnapshotInterval    time.Duration
 	}
 
 	BootstrapPeers func() []peer.AddrInfo
 	AddressFilter  func([]ma.Multiaddr) []ma.Multiaddr
 
+	ServerRateLimit struct {
+		Global  float64
+		PerPeer float64
+	}
+	ValidationConcurrency int
+	MinPutSuccesses       int
+
+	AdaptiveConcurrency struct {
+		Min int
+		Max int
+	}
+
 	// test specific Config options
 	DisableFixLowPeers          bool
 	TestAddressUpdateProcessing bool
@@ -127,6 +141,7 @@ var Defaults = func(o *Config) error {
 	o.Concurrency = 10
 	o.Resiliency = 3
 	o.LookupCheckConcurrency = 256
+	o.ValidationConcurrency = runtime.NumCPU()
 
 	// MAGIC: It makes sense to set it to a multiple of OptProvReturnRatio * BucketSize. We chose a multiple of 4.
 	o.OptimisticProvideJobsPoolSize = 60
@@ -135,6 +150,10 @@ var Defaults = func(o *Config) error {
 }
 
 func (c *Config) Validate() error {
+	if c.EnableValues && c.Validator == nil {
+		return fmt.Errorf("values are enabled but no Validator is set")
+	}
+
 	if c.ProtocolPrefix != DefaultPrefix {
 		return nil
 	}
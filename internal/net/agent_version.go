@@ -0,0 +1,69 @@
+package net
+
+import (
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+)
+
+// otherAgentVersion is the label used for peers whose agent version is missing or not a known DHT implementation.
+const otherAgentVersion = "other"
+
+// knownAgents are the implementations we report by name in the metrics. Every other agent is reported as
+// otherAgentVersion, which keeps the number of label values bounded no matter what remote peers claim to be.
+var knownAgents = map[string]struct{}{
+	"kubo":          {},
+	"go-ipfs":       {},
+	"hydra-booster": {},
+	"lotus":         {},
+	"boost":         {},
+	"rust-libp2p":   {},
+	"js-libp2p":     {},
+	"helia":         {},
+	"nim-libp2p":    {},
+}
+
+// AgentVersion returns the normalized agent version of p as recorded by identify.
+func AgentVersion(ps peerstore.Peerstore, p peer.ID) string {
+	av, err := ps.Get(p, "AgentVersion")
+	if err != nil {
+		return otherAgentVersion
+	}
+	s, ok := av.(string)
+	if !ok {
+		return otherAgentVersion
+	}
+	return normalizeAgentVersion(s)
+}
+
+// normalizeAgentVersion turns an agent version such as "kubo/0.22.1/abcdef" into a low cardinality label of the form
+// "kubo/0.22", keeping only the major and minor version of known implementations.
+func normalizeAgentVersion(av string) string {
+	name, version, _ := strings.Cut(av, "/")
+	name = strings.ToLower(name)
+	if _, ok := knownAgents[name]; !ok {
+		return otherAgentVersion
+	}
+
+	version, _, _ = strings.Cut(version, "/")
+	version = strings.TrimPrefix(version, "v")
+	major, rest, _ := strings.Cut(version, ".")
+	minor, _, _ := strings.Cut(rest, ".")
+	if !isNumeric(major) || !isNumeric(minor) {
+		return name
+	}
+	return name + "/" + major + "." + minor
+}
+
+func isNumeric(s string) bool {
+	if s == "" || len(s) > 4 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package net
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeAgentVersion(t *testing.T) {
+	for av, expected := range map[string]string{
+		"kubo/0.22.0/3f884d3":        "kubo/0.22",
+		"kubo/0.23.0-rc1/":           "kubo/0.23",
+		"go-ipfs/0.8.0/48f94e2":      "go-ipfs/0.8",
+		"hydra-booster/0.7.4":        "hydra-booster/0.7",
+		"rust-libp2p/v0.52.3":        "rust-libp2p/0.52",
+		"js-libp2p/0.46.9 UserAgent": "js-libp2p/0.46",
+		"Kubo/0.22.0":                "kubo/0.22",
+		"kubo":                       "kubo",
+		"kubo/latest":                "kubo",
+		"kubo/100000.1":              "kubo",
+		"github.com/foo/bar@v1.2.3":  "other",
+		"storm":                      "other",
+		"":                           "other",
+	} {
+		require.Equal(t, expected, normalizeAgentVersion(av), av)
+	}
+}
@@ -73,7 +73,10 @@ func (m *messageSenderImpl) OnDisconnect(ctx context.Context, p peer.ID) {
 // SendRequest sends out a request, but also makes sure to
 // measure the RTT for latency measurements.
 func (m *messageSenderImpl) SendRequest(ctx context.Context, p peer.ID, pmes *pb.Message) (*pb.Message, error) {
-	ctx, _ = tag.New(ctx, metrics.UpsertMessageType(pmes))
+	ctx, _ = tag.New(ctx,
+		metrics.UpsertMessageType(pmes),
+		tag.Upsert(metrics.KeyAgentVersion, AgentVersion(m.host.Peerstore(), p)),
+	)
 
 	ms, err := m.messageSenderForPeer(ctx, p)
 	if err != nil {
@@ -106,9 +109,54 @@ func (m *messageSenderImpl) SendRequest(ctx context.Context, p peer.ID, pmes *pb
 	return rpmes, nil
 }
 
+// SendRequests sends out the requests back to back on a single stream and reads their responses in order. It returns
+// a response or an error for each request.
+func (m *messageSenderImpl) SendRequests(ctx context.Context, p peer.ID, pmes []*pb.Message) ([]*pb.Message, []error) {
+	ctx, _ = tag.New(ctx,
+		tag.Upsert(metrics.KeyAgentVersion, AgentVersion(m.host.Peerstore(), p)),
+	)
+
+	var (
+		rpmes     = make([]*pb.Message, len(pmes))
+		latencies = make([]time.Duration, len(pmes))
+		errs      = make([]error, len(pmes))
+	)
+	ms, err := m.messageSenderForPeer(ctx, p)
+	if err != nil {
+		logger.Debugw("requests failed to open message sender", "error", err, "to", p)
+		for i := range errs {
+			errs[i] = err
+		}
+	} else {
+		rpmes, latencies, errs = ms.SendRequests(ctx, pmes)
+	}
+
+	for i, req := range pmes {
+		reqCtx, _ := tag.New(ctx, metrics.UpsertMessageType(req))
+		if errs[i] == nil {
+			stats.Record(reqCtx,
+				metrics.SentRequests.M(1),
+				metrics.SentBytes.M(int64(req.Size())),
+				metrics.OutboundRequestLatency.M(float64(latencies[i])/float64(time.Millisecond)),
+			)
+			m.host.Peerstore().RecordLatency(p, latencies[i])
+		} else {
+			logger.Debugw("pipelined request failed", "error", errs[i], "to", p)
+			stats.Record(reqCtx,
+				metrics.SentRequests.M(1),
+				metrics.SentRequestErrors.M(1),
+			)
+		}
+	}
+	return rpmes, errs
+}
+
 // SendMessage sends out a message
 func (m *messageSenderImpl) SendMessage(ctx context.Context, p peer.ID, pmes *pb.Message) error {
-	ctx, _ = tag.New(ctx, metrics.UpsertMessageType(pmes))
+	ctx, _ = tag.New(ctx,
+		metrics.UpsertMessageType(pmes),
+		tag.Upsert(metrics.KeyAgentVersion, AgentVersion(m.host.Peerstore(), p)),
+	)
 
 	ms, err := m.messageSenderForPeer(ctx, p)
 	if err != nil {
@@ -274,6 +322,11 @@ func (ms *peerMessageSender) SendRequest(ctx context.Context, pmes *pb.Message)
 	}
 	defer ms.lk.Unlock()
 
+	return ms.sendRequest(ctx, pmes)
+}
+
+// sendRequest sends a request and reads its response, ms.lk must be held.
+func (ms *peerMessageSender) sendRequest(ctx context.Context, pmes *pb.Message) (*pb.Message, error) {
 	retry := false
 	for {
 		if err := ms.prep(ctx); err != nil {
@@ -322,6 +375,99 @@ func (ms *peerMessageSender) SendRequest(ctx context.Context, pmes *pb.Message)
 	}
 }
 
+// SendRequests writes the requests to the stream without waiting for the response of one before writing the next, and
+// reads the responses in order. It returns a response or an error for each request.
+//
+// A peer resets the stream when it fails to handle a request, e.g. because it rejected the record of a PUT_VALUE,
+// which also discards the responses we had not read yet, so we cannot tell which request broke the stream. When that
+// happens, the first request that was not answered is sent on its own, like SendRequest does, and the following ones
+// are pipelined again on a new stream. This way only the requests the peer fails to handle fail.
+func (ms *peerMessageSender) SendRequests(ctx context.Context, pmes []*pb.Message) ([]*pb.Message, []time.Duration, []error) {
+	rpmes := make([]*pb.Message, len(pmes))
+	latencies := make([]time.Duration, len(pmes))
+	errs := make([]error, len(pmes))
+	failRest := func(from int, err error) ([]*pb.Message, []time.Duration, []error) {
+		for i := from; i < len(pmes); i++ {
+			errs[i] = err
+		}
+		return rpmes, latencies, errs
+	}
+
+	if err := ms.lk.Lock(ctx); err != nil {
+		return failRest(0, err)
+	}
+	defer ms.lk.Unlock()
+
+	for next := 0; next < len(pmes); next++ {
+		if err := ms.prep(ctx); err != nil {
+			return failRest(next, err)
+		}
+
+		n, err := ms.pipeline(ctx, pmes[next:], rpmes[next:], latencies[next:])
+		next += n
+		if err == nil {
+			break
+		}
+
+		_ = ms.s.Reset()
+		ms.s = nil
+		if ctx.Err() != nil {
+			// retry would be same error
+			return failRest(next, err)
+		}
+		logger.Debugw("error sending pipelined requests", "error", err, "answered", next, "sent", len(pmes))
+
+		reqCtx, _ := tag.New(ctx, metrics.UpsertMessageType(pmes[next]))
+		start := time.Now()
+		rpmes[next], errs[next] = ms.sendRequest(reqCtx, pmes[next])
+		latencies[next] = time.Since(start)
+		if ctx.Err() != nil {
+			return failRest(next+1, ctx.Err())
+		}
+	}
+	return rpmes, latencies, errs
+}
+
+// pipeline writes the requests from a separate goroutine while reading their responses, so that neither side of the
+// stream waits for the other. It stores the response of each request in rpmes as it comes in, along with the time
+// between writing the request and reading its response in latencies, and returns the number of requests answered.
+func (ms *peerMessageSender) pipeline(ctx context.Context, pmes []*pb.Message, rpmes []*pb.Message, latencies []time.Duration) (int, error) {
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := ms.s
+	writeErr := make(chan error, 1)
+	written := make(chan time.Time, len(pmes))
+	go func() {
+		for _, req := range pmes {
+			start := time.Now()
+			if err := WriteMsg(s, req); err != nil {
+				writeErr <- err
+				// the remaining responses will never come, stop waiting for them
+				cancel()
+				return
+			}
+			written <- start
+		}
+		writeErr <- nil
+	}()
+
+	for i, req := range pmes {
+		mes := new(pb.Message)
+		reqCtx, _ := tag.New(readCtx, metrics.UpsertMessageType(req))
+		if err := ms.ctxReadMsg(reqCtx, mes); err != nil {
+			if ctx.Err() == nil && readCtx.Err() != nil {
+				return i, <-writeErr
+			}
+			return i, err
+		}
+		// a response can only be read once its request was written
+		latencies[i] = time.Since(<-written)
+		rpmes[i] = mes
+	}
+	return len(pmes), <-writeErr
+}
+
 func (ms *peerMessageSender) writeMsg(pmes *pb.Message) error {
 	return WriteMsg(ms.s, pmes)
 }
@@ -333,10 +479,17 @@ func (ms *peerMessageSender) ctxReadMsg(ctx context.Context, mes *pb.Message) er
 		bytes, err := r.ReadMsg()
 		defer r.ReleaseMsg(bytes)
 		if err != nil {
+			if err == msgio.ErrMsgTooLarge {
+				stats.Record(ctx, metrics.InvalidResponses.M(1))
+			}
 			errc <- err
 			return
 		}
-		errc <- mes.Unmarshal(bytes)
+		err = mes.Unmarshal(bytes)
+		if err != nil {
+			stats.Record(ctx, metrics.InvalidResponses.M(1))
+		}
+		errc <- err
 	}(ms.r)
 
 	t := time.NewTimer(dhtReadMessageTimeout)
@@ -2,15 +2,23 @@ package net
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/protocol"
 
 	bhost "github.com/libp2p/go-libp2p/p2p/host/basic"
 	swarmt "github.com/libp2p/go-libp2p/p2p/net/swarm/testing"
 
+	"github.com/libp2p/go-libp2p-kad-dht/metrics"
+	pb "github.com/libp2p/go-libp2p-kad-dht/pb"
+	"github.com/libp2p/go-msgio"
 	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 )
 
 func TestInvalidMessageSenderTracking(t *testing.T) {
@@ -37,3 +45,218 @@ func TestInvalidMessageSenderTracking(t *testing.T) {
 		t.Fatal("should have no message senders in map")
 	}
 }
+
+func TestInvalidResponsesAreCounted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, view.Register(metrics.InvalidResponsesView))
+	defer view.Unregister(metrics.InvalidResponsesView)
+
+	const proto = protocol.ID("/test/kad/1.0.0")
+
+	server, err := bhost.NewHost(swarmt.GenSwarm(t, swarmt.OptDisableReuseport), new(bhost.HostOpts))
+	require.NoError(t, err)
+	server.Start()
+	defer server.Close()
+
+	// answer every request with a frame that is not a valid protobuf message
+	server.SetStreamHandler(proto, func(s network.Stream) {
+		defer s.Close()
+		_ = msgio.NewVarintWriter(s).WriteMsg([]byte{0xff, 0xff, 0xff})
+	})
+
+	client, err := bhost.NewHost(swarmt.GenSwarm(t, swarmt.OptDisableReuseport), new(bhost.HostOpts))
+	require.NoError(t, err)
+	client.Start()
+	defer client.Close()
+
+	require.NoError(t, client.Connect(ctx, peer.AddrInfo{ID: server.ID(), Addrs: server.Addrs()}))
+
+	msgSender := NewMessageSenderImpl(client, []protocol.ID{proto})
+	_, err = msgSender.SendRequest(ctx, server.ID(), pb.NewMessage(pb.Message_FIND_NODE, []byte("key"), 0))
+	require.Error(t, err)
+
+	rows, err := view.RetrieveData(metrics.InvalidResponsesView.Name)
+	require.NoError(t, err)
+	require.NotEmpty(t, rows)
+
+	var count int64
+	for _, row := range rows {
+		count += row.Data.(*view.CountData).Value
+		for _, tag := range row.Tags {
+			if tag.Key == metrics.KeyAgentVersion {
+				require.Equal(t, otherAgentVersion, tag.Value)
+			}
+		}
+	}
+	// the request is retried once on a new stream, which gets an invalid response too
+	require.EqualValues(t, 2, count)
+}
+
+func setupPipelineTest(t *testing.T, handler network.StreamHandler) (*messageSenderImpl, peer.ID) {
+	const proto = protocol.ID("/test/kad/1.0.0")
+
+	server, err := bhost.NewHost(swarmt.GenSwarm(t, swarmt.OptDisableReuseport), new(bhost.HostOpts))
+	require.NoError(t, err)
+	server.Start()
+	t.Cleanup(func() { server.Close() })
+	server.SetStreamHandler(proto, handler)
+
+	client, err := bhost.NewHost(swarmt.GenSwarm(t, swarmt.OptDisableReuseport), new(bhost.HostOpts))
+	require.NoError(t, err)
+	client.Start()
+	t.Cleanup(func() { client.Close() })
+
+	require.NoError(t, client.Connect(context.Background(), peer.AddrInfo{ID: server.ID(), Addrs: server.Addrs()}))
+	return NewMessageSenderImpl(client, []protocol.ID{proto}).(*messageSenderImpl), server.ID()
+}
+
+func pipelineRequests(n int) []*pb.Message {
+	reqs := make([]*pb.Message, n)
+	for i := range reqs {
+		reqs[i] = pb.NewMessage(pb.Message_PUT_VALUE, []byte(fmt.Sprintf("key-%d", i)), 0)
+	}
+	return reqs
+}
+
+func TestSendRequestsPipelines(t *testing.T) {
+	const n = 10
+
+	require.NoError(t, view.Register(metrics.OutboundRequestLatencyView))
+	defer view.Unregister(metrics.OutboundRequestLatencyView)
+
+	// read all the requests before answering any of them, which only works if the client does not wait for the
+	// response of a request before sending the next one
+	msgSender, server := setupPipelineTest(t, func(s network.Stream) {
+		defer s.Close()
+		r := msgio.NewVarintReaderSize(s, network.MessageSizeMax)
+		reqs := make([]*pb.Message, n)
+		for i := range reqs {
+			buf, err := r.ReadMsg()
+			if err != nil {
+				return
+			}
+			reqs[i] = new(pb.Message)
+			if err := reqs[i].Unmarshal(buf); err != nil {
+				return
+			}
+		}
+		for _, req := range reqs {
+			if err := WriteMsg(s, req); err != nil {
+				return
+			}
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reqs := pipelineRequests(n)
+	resps, errs := msgSender.SendRequests(ctx, server, reqs)
+	require.Len(t, resps, n)
+	for i, resp := range resps {
+		require.NoError(t, errs[i])
+		require.Equal(t, reqs[i].GetKey(), resp.GetKey())
+	}
+
+	// the latency of every answered request is recorded
+	rows, err := view.RetrieveData(metrics.OutboundRequestLatencyView.Name)
+	require.NoError(t, err)
+	var count int64
+	for _, row := range rows {
+		count += row.Data.(*view.DistributionData).Count
+	}
+	require.EqualValues(t, n, count)
+}
+
+func TestSendRequestsResumesOnNewStream(t *testing.T) {
+	// answer 3 requests per stream, then close it
+	msgSender, server := setupPipelineTest(t, func(s network.Stream) {
+		r := msgio.NewVarintReaderSize(s, network.MessageSizeMax)
+		for i := 0; i < 3; i++ {
+			buf, err := r.ReadMsg()
+			if err != nil {
+				break
+			}
+			req := new(pb.Message)
+			if err := req.Unmarshal(buf); err != nil {
+				break
+			}
+			if err := WriteMsg(s, req); err != nil {
+				break
+			}
+		}
+		_ = s.CloseWrite()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reqs := pipelineRequests(10)
+	resps, errs := msgSender.SendRequests(ctx, server, reqs)
+
+	// the requests that were not answered are sent again on new streams
+	for i, resp := range resps {
+		require.NoError(t, errs[i], i)
+		require.Equal(t, reqs[i].GetKey(), resp.GetKey())
+	}
+}
+
+func TestSendRequestsContinuesAfterRejectedRequest(t *testing.T) {
+	// reset the stream when handling key-3, like a DHT server does when it rejects a record
+	msgSender, server := setupPipelineTest(t, func(s network.Stream) {
+		r := msgio.NewVarintReaderSize(s, network.MessageSizeMax)
+		for {
+			buf, err := r.ReadMsg()
+			if err != nil {
+				_ = s.Reset()
+				return
+			}
+			req := new(pb.Message)
+			if err := req.Unmarshal(buf); err != nil || string(req.GetKey()) == "key-3" {
+				_ = s.Reset()
+				return
+			}
+			if err := WriteMsg(s, req); err != nil {
+				return
+			}
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reqs := pipelineRequests(10)
+	resps, errs := msgSender.SendRequests(ctx, server, reqs)
+
+	// only the rejected request fails
+	for i, resp := range resps {
+		if i == 3 {
+			require.Error(t, errs[i])
+			continue
+		}
+		require.NoError(t, errs[i], i)
+		require.Equal(t, reqs[i].GetKey(), resp.GetKey())
+	}
+}
+
+func TestSendRequestsInvalidResponsesAreTagged(t *testing.T) {
+	require.NoError(t, view.Register(metrics.InvalidResponsesView))
+	defer view.Unregister(metrics.InvalidResponsesView)
+
+	// answer every request with a frame that is not a valid protobuf message
+	msgSender, server := setupPipelineTest(t, func(s network.Stream) {
+		defer s.Close()
+		_ = msgio.NewVarintWriter(s).WriteMsg([]byte{0xff, 0xff, 0xff})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, errs := msgSender.SendRequests(ctx, server, pipelineRequests(1))
+	require.Error(t, errs[0])
+
+	rows, err := view.RetrieveData(metrics.InvalidResponsesView.Name)
+	require.NoError(t, err)
+	require.NotEmpty(t, rows)
+	for _, row := range rows {
+		require.Contains(t, row.Tags, tag.Tag{Key: metrics.KeyMessageType, Value: pb.Message_PUT_VALUE.String()})
+	}
+}
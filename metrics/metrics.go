@@ -19,6 +19,9 @@ var (
 	// KeyInstanceID identifies a dht instance by the pointer address.
 	// Useful for differentiating between different dhts that have the same peer id.
 	KeyInstanceID, _ = tag.NewKey("instance_id")
+	// KeyAgentVersion is the normalized agent version of the remote peer, e.g. "kubo/0.22".
+	// Unknown implementations are reported as "other" to keep the cardinality bounded.
+	KeyAgentVersion, _ = tag.NewKey("agent_version")
 )
 
 // UpsertMessageType is a convenience upserts the message type
@@ -32,6 +35,7 @@ var (
 	ReceivedMessages       = stats.Int64("libp2p.io/dht/kad/received_messages", "Total number of messages received per RPC", stats.UnitDimensionless)
 	ReceivedMessageErrors  = stats.Int64("libp2p.io/dht/kad/received_message_errors", "Total number of errors for messages received per RPC", stats.UnitDimensionless)
 	ReceivedBytes          = stats.Int64("libp2p.io/dht/kad/received_bytes", "Total received bytes per RPC", stats.UnitBytes)
+	ThrottledMessages      = stats.Int64("libp2p.io/dht/kad/throttled_messages", "Total number of received messages rejected by the server rate limits per RPC", stats.UnitDimensionless)
 	InboundRequestLatency  = stats.Float64("libp2p.io/dht/kad/inbound_request_latency", "Latency per RPC", stats.UnitMilliseconds)
 	OutboundRequestLatency = stats.Float64("libp2p.io/dht/kad/outbound_request_latency", "Latency per RPC", stats.UnitMilliseconds)
 	SentMessages           = stats.Int64("libp2p.io/dht/kad/sent_messages", "Total number of messages sent per RPC", stats.UnitDimensionless)
@@ -39,6 +43,7 @@ var (
 	SentRequests           = stats.Int64("libp2p.io/dht/kad/sent_requests", "Total number of requests sent per RPC", stats.UnitDimensionless)
 	SentRequestErrors      = stats.Int64("libp2p.io/dht/kad/sent_request_errors", "Total number of errors for requests sent per RPC", stats.UnitDimensionless)
 	SentBytes              = stats.Int64("libp2p.io/dht/kad/sent_bytes", "Total sent bytes per RPC", stats.UnitBytes)
+	InvalidResponses       = stats.Int64("libp2p.io/dht/kad/invalid_responses", "Total number of responses that could not be decoded per RPC", stats.UnitDimensionless)
 	NetworkSize            = stats.Int64("libp2p.io/dht/kad/network_size", "Network size estimation", stats.UnitDimensionless)
 )
 
@@ -59,6 +64,11 @@ var (
 		TagKeys:     []tag.Key{KeyMessageType, KeyPeerID, KeyInstanceID},
 		Aggregation: defaultBytesDistribution,
 	}
+	ThrottledMessagesView = &view.View{
+		Measure:     ThrottledMessages,
+		TagKeys:     []tag.Key{KeyMessageType, KeyAgentVersion, KeyPeerID, KeyInstanceID},
+		Aggregation: view.Count(),
+	}
 	InboundRequestLatencyView = &view.View{
 		Measure:     InboundRequestLatency,
 		TagKeys:     []tag.Key{KeyMessageType, KeyPeerID, KeyInstanceID},
@@ -66,27 +76,27 @@ var (
 	}
 	OutboundRequestLatencyView = &view.View{
 		Measure:     OutboundRequestLatency,
-		TagKeys:     []tag.Key{KeyMessageType, KeyPeerID, KeyInstanceID},
+		TagKeys:     []tag.Key{KeyMessageType, KeyAgentVersion, KeyPeerID, KeyInstanceID},
 		Aggregation: defaultMillisecondsDistribution,
 	}
 	SentMessagesView = &view.View{
 		Measure:     SentMessages,
-		TagKeys:     []tag.Key{KeyMessageType, KeyPeerID, KeyInstanceID},
+		TagKeys:     []tag.Key{KeyMessageType, KeyAgentVersion, KeyPeerID, KeyInstanceID},
 		Aggregation: view.Count(),
 	}
 	SentMessageErrorsView = &view.View{
 		Measure:     SentMessageErrors,
-		TagKeys:     []tag.Key{KeyMessageType, KeyPeerID, KeyInstanceID},
+		TagKeys:     []tag.Key{KeyMessageType, KeyAgentVersion, KeyPeerID, KeyInstanceID},
 		Aggregation: view.Count(),
 	}
 	SentRequestsView = &view.View{
 		Measure:     SentRequests,
-		TagKeys:     []tag.Key{KeyMessageType, KeyPeerID, KeyInstanceID},
+		TagKeys:     []tag.Key{KeyMessageType, KeyAgentVersion, KeyPeerID, KeyInstanceID},
 		Aggregation: view.Count(),
 	}
 	SentRequestErrorsView = &view.View{
 		Measure:     SentRequestErrors,
-		TagKeys:     []tag.Key{KeyMessageType, KeyPeerID, KeyInstanceID},
+		TagKeys:     []tag.Key{KeyMessageType, KeyAgentVersion, KeyPeerID, KeyInstanceID},
 		Aggregation: view.Count(),
 	}
 	SentBytesView = &view.View{
@@ -94,6 +104,11 @@ var (
 		TagKeys:     []tag.Key{KeyMessageType, KeyPeerID, KeyInstanceID},
 		Aggregation: defaultBytesDistribution,
 	}
+	InvalidResponsesView = &view.View{
+		Measure:     InvalidResponses,
+		TagKeys:     []tag.Key{KeyMessageType, KeyAgentVersion, KeyPeerID, KeyInstanceID},
+		Aggregation: view.Count(),
+	}
 	NetworkSizeView = &view.View{
 		Measure:     NetworkSize,
 		TagKeys:     []tag.Key{KeyPeerID, KeyInstanceID},
@@ -106,6 +121,7 @@ var DefaultViews = []*view.View{
 	ReceivedMessagesView,
 	ReceivedMessageErrorsView,
 	ReceivedBytesView,
+	ThrottledMessagesView,
 	InboundRequestLatencyView,
 	OutboundRequestLatencyView,
 	SentMessagesView,
@@ -113,5 +129,6 @@ var DefaultViews = []*view.View{
 	SentRequestsView,
 	SentRequestErrorsView,
 	SentBytesView,
+	InvalidResponsesView,
 	NetworkSizeView,
 }
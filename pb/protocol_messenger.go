@@ -53,6 +53,16 @@ type MessageSenderWithDisconnect interface {
 	OnDisconnect(context.Context, peer.ID)
 }
 
+// MessageSenderWithPipelining is a MessageSender that can send several requests to a peer without waiting for
+// the response of one before sending the next.
+type MessageSenderWithPipelining interface {
+	MessageSender
+
+	// SendRequests sends a peer the messages back to back and waits for their responses, which are returned in order.
+	// It returns a response or an error for each message, a failed message does not fail the ones after it.
+	SendRequests(ctx context.Context, p peer.ID, pmes []*Message) ([]*Message, []error)
+}
+
 // MessageSender handles sending wire protocol messages to a given peer
 type MessageSender interface {
 	// SendRequest sends a peer a message and waits for its response
@@ -91,6 +101,47 @@ func (pm *ProtocolMessenger) PutValue(ctx context.Context, p peer.ID, rec *recpb
 	return nil
 }
 
+// PutValues asks a peer to store each of the given records. The requests are pipelined if the MessageSender is
+// a MessageSenderWithPipelining, and sent one after the other otherwise. It returns an error for each record, nil if
+// the record was stored.
+func (pm *ProtocolMessenger) PutValues(ctx context.Context, p peer.ID, recs []*recpb.Record) []error {
+	errs := make([]error, len(recs))
+	ms, ok := pm.m.(MessageSenderWithPipelining)
+	if !ok {
+		for i, rec := range recs {
+			errs[i] = pm.PutValue(ctx, p, rec)
+		}
+		return errs
+	}
+
+	ctx, span := internal.StartSpan(ctx, "ProtocolMessenger.PutValues")
+	defer span.End()
+	if span.IsRecording() {
+		span.SetAttributes(attribute.Stringer("to", p), attribute.Int("records", len(recs)))
+	}
+
+	pmes := make([]*Message, len(recs))
+	for i, rec := range recs {
+		pmes[i] = NewMessage(Message_PUT_VALUE, rec.Key, 0)
+		pmes[i].Record = rec
+	}
+	rpmes, sendErrs := ms.SendRequests(ctx, p, pmes)
+	for i, rec := range recs {
+		if sendErrs[i] != nil {
+			logger.Debugw("failed to put value to peer", "to", p, "key", internal.LoggableRecordKeyBytes(rec.Key), "error", sendErrs[i])
+			span.SetStatus(codes.Error, sendErrs[i].Error())
+			errs[i] = sendErrs[i]
+			continue
+		}
+		if !bytes.Equal(rpmes[i].GetRecord().Value, rec.Value) {
+			const errStr = "value not put correctly"
+			logger.Infow(errStr, "put-message", pmes[i], "get-message", rpmes[i])
+			errs[i] = errors.New(errStr)
+		}
+	}
+	return errs
+}
+
 // GetValue asks a peer for the value corresponding to the given key. Also returns the K closest peers to the key
 // as described in GetClosestPeers.
 func (pm *ProtocolMessenger) GetValue(ctx context.Context, p peer.ID, key string) (record *recpb.Record, closerPeers []*peer.AddrInfo, err error) {
@@ -278,9 +278,14 @@ func (q *query) run() {
 	pathCtx, cancelPath := context.WithCancel(ctx)
 	defer cancelPath()
 
-	alpha := q.dht.alpha
+	alpha, maxAlpha := q.dht.alpha, q.dht.alpha
+	var ac *adaptiveConcurrency
+	if q.dht.maxAlpha > 0 {
+		ac = newAdaptiveConcurrency(alpha, q.dht.minAlpha, q.dht.maxAlpha)
+		alpha, maxAlpha = ac.limit(), q.dht.maxAlpha
+	}
 
-	ch := make(chan *queryUpdate, alpha)
+	ch := make(chan *queryUpdate, maxAlpha)
 	ch <- &queryUpdate{cause: q.dht.self, heard: q.seedPeers}
 
 	// return only once all outstanding queries have completed.
@@ -291,6 +296,10 @@ func (q *query) run() {
 		case update := <-ch:
 			q.updateState(pathCtx, update)
 			cause = update.cause
+			if ac != nil {
+				ac.observe(pathCtx, update)
+				alpha = ac.limit()
+			}
 		case <-pathCtx.Done():
 			q.terminate(pathCtx, cancelPath, LookupCancelled)
 		}
@@ -364,11 +373,11 @@ func (q *query) isReadyToTerminate(ctx context.Context, nPeersToQuery int) (bool
 	peers := q.queryPeers.GetClosestInStates(qpeerset.PeerHeard)
 	count := 0
 	for _, p := range peers {
-		peersToQuery = append(peersToQuery, p)
-		count++
-		if count == nPeersToQuery {
+		if count >= nPeersToQuery {
 			break
 		}
+		peersToQuery = append(peersToQuery, p)
+		count++
 	}
 
 	return false, -1, peersToQuery
@@ -0,0 +1,111 @@
+package dht
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// concurrencyWindow is the number of recent requests of a query path the adaptive concurrency is based on.
+const concurrencyWindow = 16
+
+// maxFailureRate is the share of failed requests in the window above which the adaptive concurrency is lowered.
+const maxFailureRate = 0.2
+
+type requestSample struct {
+	latency time.Duration
+	failed  bool
+}
+
+// adaptiveConcurrency adjusts the number of concurrent requests of a query path between min and max based on how
+// the recent requests of that path went.
+//
+// The concurrency is raised after a successful request that was not slower than the median of the recent ones, and
+// is lowered after a failed request (a dial failure or timeout) while more than maxFailureRate of the recent
+// requests failed. Responses getting slower or failing hint that we, or the peers we query, are overloaded, while
+// fast responses mean we could make more progress by querying more peers at once.
+//
+// It is only accessed from the query run loop and is not safe for concurrent use.
+type adaptiveConcurrency struct {
+	min, max int
+	current  int
+
+	samples []requestSample
+	next    int
+}
+
+func newAdaptiveConcurrency(alpha, min, max int) *adaptiveConcurrency {
+	if alpha < min {
+		alpha = min
+	} else if alpha > max {
+		alpha = max
+	}
+	return &adaptiveConcurrency{
+		min:     min,
+		max:     max,
+		current: alpha,
+		samples: make([]requestSample, 0, concurrencyWindow),
+	}
+}
+
+// limit returns the number of requests the query path should have in flight.
+func (ac *adaptiveConcurrency) limit() int {
+	return ac.current
+}
+
+// observe adjusts the concurrency to the outcome of a request reported by a query update. Requests failing because
+// the query path was cancelled say nothing about the network and are ignored.
+func (ac *adaptiveConcurrency) observe(ctx context.Context, up *queryUpdate) {
+	if len(up.queried) > 0 {
+		ac.success(up.queryDuration)
+	} else if len(up.unreachable) > 0 && ctx.Err() == nil {
+		ac.failure()
+	}
+}
+
+func (ac *adaptiveConcurrency) success(latency time.Duration) {
+	ac.add(requestSample{latency: latency})
+	if latency <= ac.medianLatency() && ac.failureRate() <= maxFailureRate && ac.current < ac.max {
+		ac.current++
+	}
+}
+
+func (ac *adaptiveConcurrency) failure() {
+	ac.add(requestSample{failed: true})
+	if ac.failureRate() > maxFailureRate && ac.current > ac.min {
+		ac.current--
+	}
+}
+
+func (ac *adaptiveConcurrency) add(s requestSample) {
+	if len(ac.samples) < concurrencyWindow {
+		ac.samples = append(ac.samples, s)
+		return
+	}
+	ac.samples[ac.next] = s
+	ac.next = (ac.next + 1) % concurrencyWindow
+}
+
+func (ac *adaptiveConcurrency) failureRate() float64 {
+	failed := 0
+	for _, s := range ac.samples {
+		if s.failed {
+			failed++
+		}
+	}
+	return float64(failed) / float64(len(ac.samples))
+}
+
+func (ac *adaptiveConcurrency) medianLatency() time.Duration {
+	latencies := make([]time.Duration, 0, len(ac.samples))
+	for _, s := range ac.samples {
+		if !s.failed {
+			latencies = append(latencies, s.latency)
+		}
+	}
+	if len(latencies) == 0 {
+		return 0
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return latencies[(len(latencies)-1)/2]
+}
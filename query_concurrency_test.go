@@ -0,0 +1,148 @@
+package dht
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveConcurrencyStartsAtAlpha(t *testing.T) {
+	require.Equal(t, 3, newAdaptiveConcurrency(3, 1, 10).limit())
+	require.Equal(t, 2, newAdaptiveConcurrency(1, 2, 10).limit())
+	require.Equal(t, 10, newAdaptiveConcurrency(20, 2, 10).limit())
+}
+
+func TestAdaptiveConcurrencyBounds(t *testing.T) {
+	ac := newAdaptiveConcurrency(3, 2, 5)
+	for i := 0; i < 20; i++ {
+		ac.success(10 * time.Millisecond)
+	}
+	require.Equal(t, 5, ac.limit())
+
+	for i := 0; i < 20; i++ {
+		ac.failure()
+	}
+	require.Equal(t, 2, ac.limit())
+}
+
+func TestAdaptiveConcurrencySlowResponses(t *testing.T) {
+	ac := newAdaptiveConcurrency(3, 1, 10)
+	ac.success(100 * time.Millisecond)
+	require.Equal(t, 4, ac.limit())
+
+	// responses getting slower than the median do not raise the concurrency
+	for i := 1; i <= 5; i++ {
+		ac.success(time.Duration(100+i*50) * time.Millisecond)
+	}
+	require.Equal(t, 4, ac.limit())
+}
+
+func TestAdaptiveConcurrencyToleratesSomeFailures(t *testing.T) {
+	ac := newAdaptiveConcurrency(5, 1, 10)
+	for i := 0; i < 9; i++ {
+		ac.success(10 * time.Millisecond)
+	}
+	// a dead peer now and then, 1 in 10 requests, does not lower the concurrency
+	ac.failure()
+	require.Equal(t, 10, ac.limit())
+}
+
+func TestAdaptiveConcurrencyIgnoresCancelledRequests(t *testing.T) {
+	ac := newAdaptiveConcurrency(5, 1, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ac.observe(ctx, &queryUpdate{unreachable: []peer.ID{"a"}})
+	require.Empty(t, ac.samples)
+	ac.observe(context.Background(), &queryUpdate{unreachable: []peer.ID{"a"}})
+	require.Equal(t, 4, ac.limit())
+}
+
+// simulateQueryPath sends requests through a query path whose concurrency is set by limit, against a mock network
+// where every request in flight adds latency, like an overloaded link or peer. Requests slower than timeout fail.
+// It returns how many of the requests timed out.
+func simulateQueryPath(requests int, limit func() int, done func(latency time.Duration, failed bool)) int {
+	const (
+		latencyPerRequest = 200 * time.Millisecond
+		timeout           = 1200 * time.Millisecond
+	)
+
+	type request struct {
+		end     time.Time
+		latency time.Duration
+		failed  bool
+	}
+
+	var (
+		now      time.Time
+		inflight []request
+		timeouts int
+	)
+	for started := 0; started < requests || len(inflight) > 0; {
+		for len(inflight) < limit() && started < requests {
+			started++
+			latency := time.Duration(len(inflight)+1) * latencyPerRequest
+			if latency > timeout {
+				inflight = append(inflight, request{end: now.Add(timeout), failed: true})
+			} else {
+				inflight = append(inflight, request{end: now.Add(latency), latency: latency})
+			}
+		}
+
+		sort.Slice(inflight, func(i, j int) bool { return inflight[i].end.Before(inflight[j].end) })
+		r := inflight[0]
+		inflight = inflight[1:]
+		now = r.end
+		if r.failed {
+			timeouts++
+		}
+		done(r.latency, r.failed)
+	}
+	return timeouts
+}
+
+func TestAdaptiveConcurrencyHighLatencyNetwork(t *testing.T) {
+	const requests = 500
+
+	fixed := simulateQueryPath(requests, func() int { return 10 }, func(time.Duration, bool) {})
+
+	ac := newAdaptiveConcurrency(10, 1, 20)
+	adaptive := simulateQueryPath(requests, ac.limit, func(latency time.Duration, failed bool) {
+		if failed {
+			ac.failure()
+		} else {
+			ac.success(latency)
+		}
+	})
+
+	t.Logf("timeouts with fixed concurrency: %d, with adaptive concurrency: %d", fixed, adaptive)
+	require.Less(t, adaptive, fixed/3)
+}
+
+func TestAdaptiveConcurrencyQuery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dhts := setupDHTS(t, ctx, 4, Concurrency(2), AdaptiveConcurrency(1, 4))
+	defer func() {
+		for _, d := range dhts {
+			d.Close()
+			d.host.Close()
+		}
+	}()
+
+	connect(t, ctx, dhts[0], dhts[1])
+	connect(t, ctx, dhts[1], dhts[2])
+	connect(t, ctx, dhts[1], dhts[3])
+
+	ctxT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	p, err := dhts[0].FindPeer(ctxT, dhts[2].PeerID())
+	require.NoError(t, err)
+	require.Equal(t, dhts[2].PeerID(), p.ID)
+}
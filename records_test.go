@@ -308,6 +308,95 @@ func TestPubkeyGoodKeyFromDHTGoodKeyDirect(t *testing.T) {
 	}
 }
 
+// Check that a node answers public key requests from its peerstore,
+// without having a stored record for the key
+func TestPubkeyServedFromPeerstore(t *testing.T) {
+	ctx := context.Background()
+
+	dhtA := setupDHT(ctx, t, false)
+	dhtB := setupDHT(ctx, t, false)
+
+	defer dhtA.Close()
+	defer dhtB.Close()
+	defer dhtA.host.Close()
+	defer dhtB.host.Close()
+
+	connect(t, ctx, dhtA, dhtB)
+
+	r := u.NewSeededRand(16) // generate deterministic keypair
+	_, pubk, err := ci.GenerateKeyPairWithReader(ci.RSA, 2048, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPublicKey(pubk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RSA keys can't be extracted from the peer ID, so only node B knows it
+	if err := dhtB.peerstore.AddPubKey(id, pubk); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, _, err := dhtA.protoMessenger.GetValue(ctx, dhtB.self, routing.KeyForPublicKey(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec == nil {
+		t.Fatal("node B did not answer with the public key")
+	}
+	rpubk, err := ci.UnmarshalPublicKey(rec.GetValue())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pubk.Equals(rpubk) {
+		t.Fatal("got incorrect public key")
+	}
+
+	// the key can also be found through the DHT
+	rpubk, err = dhtA.getPublicKeyFromDHT(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pubk.Equals(rpubk) {
+		t.Fatal("got incorrect public key")
+	}
+
+	// inlined keys are extracted from the peer ID
+	identity := tnet.RandIdentityOrFatal(t)
+	rec, _, err = dhtA.protoMessenger.GetValue(ctx, dhtB.self, routing.KeyForPublicKey(identity.ID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec == nil {
+		t.Fatal("node B did not answer with the inlined public key")
+	}
+	rpubk, err = ci.UnmarshalPublicKey(rec.GetValue())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !identity.PublicKey().Equals(rpubk) {
+		t.Fatal("got incorrect public key")
+	}
+
+	// keys we don't know about are not found
+	_, unknown, err := ci.GenerateKeyPairWithReader(ci.RSA, 2048, u.NewSeededRand(17))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownID, err := peer.IDFromPublicKey(unknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, _, err = dhtA.protoMessenger.GetValue(ctx, dhtB.self, routing.KeyForPublicKey(unknownID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec != nil {
+		t.Fatal("expected no record for an unknown public key")
+	}
+}
+
 func TestValuesDisabled(t *testing.T) {
 	for i := 0; i < 3; i++ {
 		enabledA := (i & 0x1) > 0
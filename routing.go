@@ -40,6 +40,11 @@ func (dht *IpfsDHT) PutValue(ctx context.Context, key string, value []byte, opts
 		return routing.ErrNotSupported
 	}
 
+	var cfg routing.Options
+	if err := cfg.Apply(opts...); err != nil {
+		return err
+	}
+
 	logger.Debugw("putting value", "key", internal.LoggableRecordKeyString(key))
 
 	// don't even allow local users to put bad values.
@@ -72,12 +77,21 @@ func (dht *IpfsDHT) PutValue(ctx context.Context, key string, value []byte, opts
 		return err
 	}
 
+	// an offline put only stores the record locally
+	if cfg.Offline {
+		return nil
+	}
+
 	peers, err := dht.GetClosestPeers(ctx, key)
 	if err != nil {
 		return err
 	}
 
-	wg := sync.WaitGroup{}
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []error
+	)
 	for _, p := range peers {
 		wg.Add(1)
 		go func(p peer.ID) {
@@ -92,11 +106,17 @@ func (dht *IpfsDHT) PutValue(ctx context.Context, key string, value []byte, opts
 			err := dht.protoMessenger.PutValue(ctx, p, rec)
 			if err != nil {
 				logger.Debugf("failed putting value to peer: %s", err)
+				mu.Lock()
+				failed = append(failed, fmt.Errorf("peer %s: %w", p, err))
+				mu.Unlock()
 			}
 		}(p)
 	}
 	wg.Wait()
 
+	if successes := len(peers) - len(failed); successes < dht.minPutSuccesses {
+		return fmt.Errorf("put value to %d peers, want at least %d: %w", successes, dht.minPutSuccesses, errors.Join(failed...))
+	}
 	return nil
 }
 
@@ -0,0 +1,163 @@
+package dht
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	pb "github.com/libp2p/go-libp2p-kad-dht/pb"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-msgio/protoio"
+)
+
+// rtSnapshotKey is the datastore key the Routing Table snapshot is stored under.
+// The version suffix allows the encoding to change without misreading older snapshots.
+var rtSnapshotKey = ds.NewKey("/routing-table/snapshot/v1")
+
+// runRTSnapshotLoop periodically persists the Routing Table peers to the datastore.
+func (dht *IpfsDHT) runRTSnapshotLoop() {
+	dht.wg.Add(1)
+	go func() {
+		defer dht.wg.Done()
+
+		ticker := time.NewTicker(dht.rtSnapshotInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := dht.writeRTSnapshot(dht.ctx); err != nil {
+					logger.Warnw("failed to persist routing table snapshot", "error", err)
+				}
+			case <-dht.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// saveRTSnapshot persists the Routing Table peers one last time when the DHT is closed.
+func (dht *IpfsDHT) saveRTSnapshot() error {
+	if dht.rtSnapshotInterval <= 0 {
+		return nil
+	}
+	return dht.writeRTSnapshot(context.Background())
+}
+
+// writeRTSnapshot stores the Routing Table peers and their known addresses in the datastore as a sequence of
+// length-prefixed pb.Message_Peer entries. An empty Routing Table never overwrites an existing snapshot, so
+// losing all our peers does not also lose the peers we could use to recover.
+func (dht *IpfsDHT) writeRTSnapshot(ctx context.Context) error {
+	var buf bytes.Buffer
+	w := protoio.NewDelimitedWriter(&buf)
+
+	n := 0
+	for _, p := range dht.routingTable.ListPeers() {
+		addrs := dht.peerstore.Addrs(p)
+		if len(addrs) == 0 {
+			continue
+		}
+		pbp := pb.RawPeerInfosToPBPeers([]peer.AddrInfo{{ID: p, Addrs: addrs}})[0]
+		if err := w.WriteMsg(&pbp); err != nil {
+			return err
+		}
+		n++
+	}
+
+	if n == 0 {
+		return nil
+	}
+	return dht.datastore.Put(ctx, rtSnapshotKey, buf.Bytes())
+}
+
+// loadRTSnapshot returns the peers persisted by the last call to writeRTSnapshot.
+// It returns nil, nil if no snapshot has been stored yet.
+func (dht *IpfsDHT) loadRTSnapshot(ctx context.Context) ([]peer.AddrInfo, error) {
+	data, err := dht.datastore.Get(ctx, rtSnapshotKey)
+	if errors.Is(err, ds.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	r := protoio.NewDelimitedReader(bytes.NewReader(data), network.MessageSizeMax)
+	var peers []peer.AddrInfo
+	for {
+		var pbp pb.Message_Peer
+		if err := r.ReadMsg(&pbp); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		ai := pb.PBPeerToPeerInfo(pbp)
+		if ai.ID == dht.self || len(ai.Addrs) == 0 {
+			continue
+		}
+		peers = append(peers, ai)
+	}
+	return peers, nil
+}
+
+// connectToRTSnapshotPeers connects to the peers of the persisted Routing Table snapshot and returns the number of
+// peers it successfully connected to. Connected peers will be added to the Routing Table once Identify completes.
+//
+// We stop after connecting to a bucket's worth of peers, which is enough to seed the Routing Table; the refresh
+// takes care of the rest.
+func (dht *IpfsDHT) connectToRTSnapshotPeers() int {
+	snapshot, err := dht.loadRTSnapshot(dht.ctx)
+	if err != nil {
+		logger.Warnw("failed to load routing table snapshot", "error", err)
+		return 0
+	}
+	if len(snapshot) == 0 {
+		return 0
+	}
+
+	ctx, cancel := context.WithCancel(dht.ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		found int
+	)
+	tokens := make(chan struct{}, dht.alpha)
+
+loop:
+	for _, i := range rand.Perm(len(snapshot)) {
+		select {
+		case tokens <- struct{}{}:
+		case <-ctx.Done():
+			break loop
+		}
+
+		wg.Add(1)
+		go func(ai peer.AddrInfo) {
+			defer wg.Done()
+			defer func() { <-tokens }()
+
+			if err := dht.host.Connect(ctx, ai); err != nil {
+				logger.Debugw("failed to connect to routing table snapshot peer", "peer", ai.ID, "error", err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			found++
+			if found >= dht.bucketSize {
+				cancel()
+			}
+		}(snapshot[i])
+	}
+	wg.Wait()
+
+	return found
+}
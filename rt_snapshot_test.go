@@ -0,0 +1,75 @@
+package dht
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRTSnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	d1 := setupDHT(ctx, t, false, RoutingTableSnapshotInterval(time.Hour))
+	d2 := setupDHT(ctx, t, false)
+	d3 := setupDHT(ctx, t, false)
+
+	// nothing to load before the first snapshot
+	snapshot, err := d1.loadRTSnapshot(ctx)
+	require.NoError(t, err)
+	require.Empty(t, snapshot)
+
+	// an empty routing table must not produce a snapshot
+	require.NoError(t, d1.writeRTSnapshot(ctx))
+	_, err = d1.datastore.Get(ctx, rtSnapshotKey)
+	require.ErrorIs(t, err, ds.ErrNotFound)
+
+	connect(t, ctx, d1, d2)
+	connect(t, ctx, d1, d3)
+	waitForWellFormedTables(t, []*IpfsDHT{d1}, 2, 2, 2*time.Second)
+
+	require.NoError(t, d1.writeRTSnapshot(ctx))
+	snapshot, err = d1.loadRTSnapshot(ctx)
+	require.NoError(t, err)
+	require.Len(t, snapshot, 2)
+	for _, ai := range snapshot {
+		require.Contains(t, []peer.ID{d2.self, d3.self}, ai.ID)
+		require.ElementsMatch(t, d1.peerstore.Addrs(ai.ID), ai.Addrs)
+	}
+}
+
+func TestRTSnapshotRestoreSkipsBootstrapPeers(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+
+	var bootstrapCalls int32
+	bootstrappers := BootstrapPeersFunc(func() []peer.AddrInfo {
+		atomic.AddInt32(&bootstrapCalls, 1)
+		return nil
+	})
+
+	d1 := setupDHT(ctx, t, false, Datastore(dstore), RoutingTableSnapshotInterval(time.Hour), bootstrappers)
+	d2 := setupDHT(ctx, t, false)
+	d3 := setupDHT(ctx, t, false)
+
+	connect(t, ctx, d1, d2)
+	connect(t, ctx, d1, d3)
+	waitForWellFormedTables(t, []*IpfsDHT{d1}, 2, 2, 2*time.Second)
+
+	// closing the DHT persists its routing table
+	require.NoError(t, d1.Close())
+	require.NoError(t, d1.host.Close())
+	atomic.StoreInt32(&bootstrapCalls, 0)
+
+	// a DHT started on the same datastore reconnects to the persisted peers
+	// without asking for the bootstrap peers.
+	restarted := setupDHT(ctx, t, false, Datastore(dstore), RoutingTableSnapshotInterval(time.Hour), bootstrappers)
+	waitForWellFormedTables(t, []*IpfsDHT{restarted}, 2, 2, 5*time.Second)
+	require.ElementsMatch(t, []peer.ID{d2.self, d3.self}, restarted.routingTable.ListPeers())
+	require.Zero(t, atomic.LoadInt32(&bootstrapCalls))
+}
@@ -61,6 +61,8 @@ func (dht *IpfsDHT) startNetworkSubscriber() error {
 					if dht.autoRefresh || dht.testAddressUpdateProcessing {
 						dht.rtRefreshManager.RefreshNoWait()
 					}
+					// we may have just come back online, resume fixing the routing table if it was paused.
+					dht.fixRTIfNeeded()
 				case event.EvtPeerProtocolsUpdated:
 					handlePeerChangeEvent(dht, evt.Peer)
 				case event.EvtPeerIdentificationCompleted: